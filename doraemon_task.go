@@ -35,7 +35,7 @@ func DoDataJob(task Task, inputFiles []string, outputFile string, arg interface{
 
 func main() {
 	flag.Usage = Usage
-	input := flag.String("i", "", "Input file")
+	input := flag.String("i", "", "Input files separated by ',', each a list of files, directories or glob patterns separated by ':'")
 	output := flag.String("o", "", "Output file")
 	conf := flag.String("c", "", "Conf File")
 
@@ -47,24 +47,38 @@ func main() {
 
 	serviceType := flag.String("s", "", "Service type["+serviceTypeSupport+"]")
 	serviceArg := flag.String("a", "", "Service Argument")
+	rollback := flag.Bool("r", false, "Rollback published results of service type to the previous version, or to the version given by -a")
 
 	flag.Parse()
 
-	if *input == "" {
+	if *conf == "" {
 		Usage()
 	}
 
-	if *output == "" {
-		Usage()
+	var err error
+	err = config.NewConfigFile("json", *conf, model.GlobalConf)
+	checkErr(err)
+
+	if *rollback {
+		if *serviceType == "" {
+			Usage()
+		}
+
+		var version string
+		version, err = task.RollbackResult(*serviceType, *serviceArg)
+		checkErr(err)
+
+		fmt.Printf("%s rollback to version %s\n", *serviceType, version)
+		return
 	}
 
-	if *conf == "" {
+	if *input == "" {
 		Usage()
 	}
 
-	var err error
-	err = config.NewConfigFile("json", *conf, model.GlobalConf)
-	checkErr(err)
+	if *output == "" {
+		Usage()
+	}
 
 	inputFiles := strings.Split(strings.TrimSpace(*input), ",")
 	outputFile := *output
@@ -2,14 +2,121 @@ package model
 
 import (
 	"fmt"
+
+	"doraemon/modual/reader"
 )
 
 var GlobalConf = NewConf()
 
+// ScoreConf selects how the activity of a task is weighted by its age.
+type ScoreConf struct {
+	Mode         string  // window: 近期与全部次数按比例加权; decay: 指数衰减; gravity: HN式重力衰减
+	HalfLifeDays float64 // decay模式下权重减半的天数
+	Gravity      float64 // gravity模式下的重力系数
+}
+
+// SmoothingConf selects how raw project scores are smoothed.
+type SmoothingConf struct {
+	Mode        string  // none: 不平滑; bayes: 贝叶斯平均; wilson: 参与用户比例的Wilson置信下限
+	PriorWeight float64 // bayes模式下先验的权重(关注人数)，0为使用项目平均关注人数
+	Z           float64 // wilson模式下置信水平对应的z值
+}
+
+// NewProjectConf controls how newly created projects are promoted.
+type NewProjectConf struct {
+	Boost      float64 // 新项目得分的最大提升比例，0为不提升
+	BoostDays  float64 // 提升曲线的天数: exp为半衰期, linear为提升消失的天数
+	Curve      string  // 提升曲线: exp或linear
+	Slots      int     // 推荐列表中为新项目保留的位置数
+	MaxAgeDays float64 // 可以占用保留位置的项目最大创建天数
+}
+
+// DiversityConf controls how a ranked list is re-ranked for diversity.
+type DiversityConf struct {
+	Mode           string  // none: 不重排; mmr: 最大边际相关性; cap: 限制相似条目数量
+	Similarity     string  // 相似度函数名称
+	Lambda         float64 // mmr模式下相关性的权重, 1为只看相关性, 0为只看多样性
+	Threshold      float64 // cap模式下相似度达到该值的条目视为相似
+	MaxSimilar     int     // cap模式下相似条目的最大数量
+	CandidateCount int     // 参与重排的候选条目数
+}
+
+// ActivityConf controls how the last sign in of a user affects its score.
+type ActivityConf struct {
+	InactiveDays        float64 // 超过该天数未登录的用户不推荐，0为不过滤
+	RecencyHalfLifeDays float64 // 登录时间衰减的半衰期天数
+	RecencyWeight       float64 // 登录时间因子的权重, 0为不使用, 1为得分完全随登录时间衰减
+}
+
+// UserCapConf limits how much a single user's activity counts.
+type UserCapConf struct {
+	MaxActions  int     // 每个用户计入的最近行为数上限，0为不限制
+	Mode        string  // events: 按行为次数计数; distinct: 每个用户最多计1次; log: 按log(1+次数)计数
+	OwnerWeight float64 // 项目创建者在自己项目中行为的权重，0为不计入
+}
+
+// RelationStatusConf is a status of the project relation file, the relation
+// it changes and the weight of a user whose latest status in that relation
+// it is.
+type RelationStatusConf struct {
+	Status   string  // 关系状态, 如follow、join、unfollow、leave
+	Relation string  // 状态所属的关系: follow(关注)或member(加入)，两种关系分别计算
+	Weight   float64 // 用户当前处于该状态时的权重，0表示已取消关注/退出
+}
+
+// CommentTypeConf is the weight of the comments on one commentable_type.
+type CommentTypeConf struct {
+	Type   string  // 评论对象类型, 如project、idea
+	Weight float64 // 该类型评论的权重
+}
+
+// RejectConf controls what happens to input lines that do not fit their
+// schema.
+type RejectConf struct {
+	File     string  // 记录被跳过的行的文件，为空时不记录
+	MaxRatio float64 // 每个输入允许跳过的行的最大比例，超过时任务失败
+}
+
 type Conf struct {
-	AppName       string
-	LogName       string
-	MemcachedHost string
+	AppName               string
+	LogName               string
+	MemcachedHost         string // memcached地址，多个地址以逗号分隔
+	MemcachedPublish      bool   // 是否将推荐结果写入memcached
+	MemcachedKeyPrefix    string // memcached key前缀，为空时使用AppName
+	MemcachedExpiration   int32  // memcached过期时间(秒)，0为永不过期
+	MemcachedKeepVersions int    // memcached中保留的结果版本数，用于回滚
+	VerboseOutput         bool   // 是否在推荐结果中输出各项特征的值、权重和对得分的贡献
+
+	PageRankDamping               float64 // PageRank阻尼系数
+	PageRankIterations            int     // PageRank最大迭代次数
+	PageRankEpsilon               float64 // PageRank收敛阈值
+	UserRecommendInfluencePercent float64 // 用户推荐中影响力得分的权重，0为不使用
+	UserRecommendReceivedPercent  float64 // 用户推荐中收到的创意评论得分的权重，0为不使用
+
+	AlsFactors        int     // ALS隐因子个数
+	AlsRegularization float64 // ALS正则化系数
+	AlsAlpha          float64 // ALS置信度权重, 置信度为1+AlsAlpha*交互次数
+	AlsIterations     int     // ALS迭代次数
+	AlsFactorFile     string  // ALS因子保存文件，为空时保存在输出文件名加.factors
+
+	ProjectRecommendScore ScoreConf // 项目推荐得分的时间加权方式
+	UserRecommendScore    ScoreConf // 用户推荐得分的时间加权方式
+
+	ProjectRecommendSmoothing  SmoothingConf  // 项目推荐得分的平滑方式
+	ProjectRecommendNewProject NewProjectConf // 项目推荐中新项目的提升方式
+	ProjectRecommendUserCap    UserCapConf    // 项目推荐中单个用户行为的计数方式
+
+	ProjectRecommendRelationStatus []RelationStatusConf // 项目关系文件中识别的状态，其他状态被忽略
+	CommentTypes                   []CommentTypeConf    // 各类型评论的权重，未配置的类型权重为1
+
+	ProjectRecommendDiversity DiversityConf // 项目推荐结果的多样性重排方式
+	UserRecommendDiversity    DiversityConf // 用户推荐结果的多样性重排方式
+
+	UserRecommendActivity ActivityConf // 用户推荐中的活跃度过滤与加权方式
+
+	Inputs    map[string]reader.Schema // 各输入文件的列定义，以输入名称为key
+	InputTime reader.TimeFormat        // 输入文件中时间列的格式和时区，列定义中未配置时使用
+	Rejects   RejectConf               // 输入文件中不符合列定义的行的处理方式
 }
 
 func (this *Conf) String() string {
@@ -20,5 +127,43 @@ func (this *Conf) String() string {
 }
 
 func NewConf() *Conf {
-	return &Conf{}
+	return &Conf{
+		MemcachedKeepVersions:      3,
+		PageRankDamping:            0.85,
+		PageRankIterations:         100,
+		PageRankEpsilon:            1e-6,
+		AlsFactors:                 20,
+		AlsRegularization:          0.1,
+		AlsAlpha:                   40,
+		AlsIterations:              15,
+		ProjectRecommendScore:      ScoreConf{Mode: "window", HalfLifeDays: 7, Gravity: 1.8},
+		UserRecommendScore:         ScoreConf{Mode: "window", HalfLifeDays: 7, Gravity: 1.8},
+		ProjectRecommendSmoothing:  SmoothingConf{Mode: "none", Z: 1.96},
+		ProjectRecommendNewProject: NewProjectConf{BoostDays: 7, Curve: "exp", MaxAgeDays: 30},
+		ProjectRecommendUserCap:    UserCapConf{Mode: "events", OwnerWeight: 1},
+		ProjectRecommendRelationStatus: []RelationStatusConf{
+			{Status: "follow", Relation: "follow", Weight: 1},
+			{Status: "join", Relation: "member", Weight: 1},
+			{Status: "unfollow", Relation: "follow", Weight: 0},
+			{Status: "leave", Relation: "member", Weight: 0},
+		},
+		CommentTypes: []CommentTypeConf{
+			{Type: "project", Weight: 1},
+			{Type: "idea", Weight: 1},
+		},
+		ProjectRecommendDiversity: DiversityConf{Mode: "none", Similarity: "owner_title", Lambda: 0.7, Threshold: 1, MaxSimilar: 2, CandidateCount: 100},
+		UserRecommendDiversity:    DiversityConf{Mode: "none", Similarity: "followers", Lambda: 0.7, Threshold: 0.5, MaxSimilar: 2, CandidateCount: 100},
+		UserRecommendActivity:     ActivityConf{RecencyHalfLifeDays: 30},
+		Inputs: map[string]reader.Schema{
+			"projects":      {Format: "auto", Table: "projects", Columns: []reader.Column{{Name: "id", Type: "int"}, {Name: "title", Type: "string"}, {Name: "user_id", Type: "int"}, {Name: "created_at", Type: "time"}}},
+			"ideas":         {Format: "auto", Table: "ideas", Columns: []reader.Column{{Name: "id", Type: "int"}, {Name: "project_id", Type: "int"}, {Name: "user_id", Type: "int"}, {Name: "created_at", Type: "time"}}},
+			"comments":      {Format: "auto", Table: "comments", Columns: []reader.Column{{Name: "id", Type: "int"}, {Name: "project_id", Type: "int"}, {Name: "user_id", Type: "int"}, {Name: "commentable_id", Type: "int"}, {Name: "commentable_type", Type: "string"}, {Name: "created_at", Type: "time"}}},
+			"user_projects": {Format: "auto", Table: "user_projects", Columns: []reader.Column{{Name: "id", Type: "int"}, {Name: "project_id", Type: "int"}, {Name: "user_id", Type: "int"}, {Name: "status", Type: "string"}, {Name: "created_at", Type: "time"}}},
+			"users":         {Format: "auto", Table: "users", Columns: []reader.Column{{Name: "id", Type: "int"}, {Name: "username", Type: "string"}, {Name: "last_sign_in_at", Type: "time"}, {Name: "created_at", Type: "time"}}},
+			"profiles":      {Format: "auto", Table: "profiles", Columns: []reader.Column{{Name: "id", Type: "int"}, {Name: "user_id", Type: "int"}, {Name: "description", Type: "string"}, {Name: "created_at", Type: "time"}}},
+			"relationships": {Format: "auto", Table: "relationships", Columns: []reader.Column{{Name: "id", Type: "int"}, {Name: "follower_id", Type: "int"}, {Name: "followed_id", Type: "int"}, {Name: "created_at", Type: "time"}}},
+		},
+		InputTime: reader.TimeFormat{Layouts: []string{reader.DefaultTimeLayout, reader.LayoutRFC3339}, Location: "Asia/Shanghai"},
+		Rejects:   RejectConf{MaxRatio: 0.01},
+	}
 }
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"fmt"
+)
+
+type Project struct {
+	Id        int64  `json:"id"`
+	Title     string `json:"title"`
+	UserId    int64  `json:"user_id"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+func (this *Project) String() string {
+	if this == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("[Project](%+v)", *this)
+}
+
+// ProjectRelation is a status change of a user in a project.
+type ProjectRelation struct {
+	Status    string  `json:"status"`
+	Relation  string  `json:"relation"`
+	Weight    float64 `json:"weight"`
+	CreatedAt int64   `json:"created_at"`
+}
+
+func (this *ProjectRelation) String() string {
+	if this == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("[ProjectRelation](%+v)", *this)
+}
@@ -5,10 +5,11 @@ import (
 )
 
 type UserRecommend struct {
-	Id          int64   `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Score       float64 `json:"score"`
+	Id          int64          `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Score       float64        `json:"score"`
+	Features    []ScoreFeature `json:"features,omitempty"`
 }
 
 func (this *UserRecommend) String() string {
@@ -18,10 +19,48 @@ func (this *UserRecommend) String() string {
 	return fmt.Sprintf("[UserRecommend](%+v)", *this)
 }
 
+type PersonalUserRecommend struct {
+	UserId int64           `json:"user_id"`
+	Users  []UserRecommend `json:"users"`
+}
+
+func (this *PersonalUserRecommend) String() string {
+	if this == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("[PersonalUserRecommend](%+v)", *this)
+}
+
+// ScoreFeature is one term of a recommendation score. Contribution is
+// Value*Weight for additive features. For multiplicative factors Value is
+// the factor, Weight the score it applies to and Contribution the change of
+// the score.
+type ScoreFeature struct {
+	Name         string  `json:"name"`
+	Value        float64 `json:"value"`
+	Weight       float64 `json:"weight"`
+	Contribution float64 `json:"contribution"`
+}
+
+func (this *ScoreFeature) String() string {
+	if this == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("[ScoreFeature](%+v)", *this)
+}
+
 type ProjectRecommend struct {
-	Id    int64   `json:"id"`
-	Title string  `json:"title"`
-	Score float64 `json:"score"`
+	Id              int64          `json:"id"`
+	Title           string         `json:"title"`
+	UserId          int64          `json:"user_id,omitempty"`
+	CreatedAt       int64          `json:"created_at,omitempty"`
+	Users           int64          `json:"users,omitempty"`
+	Followers       int64          `json:"followers,omitempty"`
+	FormerFollowers int64          `json:"former_followers,omitempty"`
+	Members         int64          `json:"members,omitempty"`
+	FormerMembers   int64          `json:"former_members,omitempty"`
+	Score           float64        `json:"score"`
+	Features        []ScoreFeature `json:"features,omitempty"`
 }
 
 func (this *ProjectRecommend) String() string {
@@ -30,3 +69,41 @@ func (this *ProjectRecommend) String() string {
 	}
 	return fmt.Sprintf("[ProjectRecommend](%+v)", *this)
 }
+
+type PersonalProjectRecommend struct {
+	UserId   int64              `json:"user_id"`
+	Projects []ProjectRecommend `json:"projects"`
+}
+
+func (this *PersonalProjectRecommend) String() string {
+	if this == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("[PersonalProjectRecommend](%+v)", *this)
+}
+
+type SimilarProject struct {
+	Id       int64              `json:"id"`
+	Title    string             `json:"title"`
+	Projects []ProjectRecommend `json:"projects"`
+}
+
+func (this *SimilarProject) String() string {
+	if this == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("[SimilarProject](%+v)", *this)
+}
+
+type UserInfluence struct {
+	Id    int64   `json:"id"`
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+}
+
+func (this *UserInfluence) String() string {
+	if this == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("[UserInfluence](%+v)", *this)
+}
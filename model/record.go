@@ -0,0 +1,113 @@
+package model
+
+import (
+	"fmt"
+)
+
+// ProjectRecord is a line of the project file.
+type ProjectRecord struct {
+	Id        int64  `reader:"id,required"`
+	Title     string `reader:"title,required"`
+	UserId    int64  `reader:"user_id,required"`
+	CreatedAt int64  `reader:"created_at,required"`
+}
+
+func (this *ProjectRecord) String() string {
+	if this == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("[ProjectRecord](%+v)", *this)
+}
+
+// IdeaRecord is a line of the idea file.
+type IdeaRecord struct {
+	Id        int64 `reader:"id"`
+	ProjectId int64 `reader:"project_id,required"`
+	UserId    int64 `reader:"user_id,required"`
+	CreatedAt int64 `reader:"created_at,required"`
+}
+
+func (this *IdeaRecord) String() string {
+	if this == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("[IdeaRecord](%+v)", *this)
+}
+
+// CommentRecord is a line of the comment file.
+type CommentRecord struct {
+	Id              int64  `reader:"id"`
+	ProjectId       int64  `reader:"project_id,required"`
+	UserId          int64  `reader:"user_id,required"`
+	CommentableId   int64  `reader:"commentable_id"`
+	CommentableType string `reader:"commentable_type"`
+	CreatedAt       int64  `reader:"created_at,required"`
+}
+
+func (this *CommentRecord) String() string {
+	if this == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("[CommentRecord](%+v)", *this)
+}
+
+// UserProjectRecord is a line of the user project relation file.
+type UserProjectRecord struct {
+	Id        int64  `reader:"id"`
+	ProjectId int64  `reader:"project_id,required"`
+	UserId    int64  `reader:"user_id,required"`
+	Status    string `reader:"status"`
+	CreatedAt int64  `reader:"created_at,required"`
+}
+
+func (this *UserProjectRecord) String() string {
+	if this == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("[UserProjectRecord](%+v)", *this)
+}
+
+// UserRecord is a line of the user file.
+type UserRecord struct {
+	Id           int64  `reader:"id,required"`
+	Username     string `reader:"username,required"`
+	LastSignInAt int64  `reader:"last_sign_in_at"`
+	CreatedAt    int64  `reader:"created_at,required"`
+}
+
+func (this *UserRecord) String() string {
+	if this == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("[UserRecord](%+v)", *this)
+}
+
+// ProfileRecord is a line of the user profile file.
+type ProfileRecord struct {
+	Id          int64  `reader:"id"`
+	UserId      int64  `reader:"user_id,required"`
+	Description string `reader:"description"`
+	CreatedAt   int64  `reader:"created_at,required"`
+}
+
+func (this *ProfileRecord) String() string {
+	if this == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("[ProfileRecord](%+v)", *this)
+}
+
+// RelationshipRecord is a line of the user relation file.
+type RelationshipRecord struct {
+	Id         int64 `reader:"id"`
+	FollowerId int64 `reader:"follower_id,required"`
+	FollowedId int64 `reader:"followed_id,required"`
+	CreatedAt  int64 `reader:"created_at,required"`
+}
+
+func (this *RelationshipRecord) String() string {
+	if this == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("[RelationshipRecord](%+v)", *this)
+}
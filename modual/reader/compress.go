@@ -0,0 +1,70 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressions are the compressions selected by the extension of the input
+// file when it does not start with the magic bytes of one.
+var Compressions = map[string]string{
+	".gz":  "gzip",
+	".bz2": "bzip2",
+	".zst": "zstd",
+}
+
+var magics = []struct {
+	name  string
+	magic []byte
+}{
+	{"gzip", []byte{0x1f, 0x8b}},
+	{"bzip2", []byte("BZh")},
+	{"zstd", []byte{0x28, 0xb5, 0x2f, 0xfd}},
+}
+
+// Decompress returns the decompressed content of the input file filename.
+// Concatenated gzip members are read as one stream.
+func Decompress(input io.Reader, filename string) (io.ReadCloser, error) {
+	br := bufio.NewReader(input)
+	head, _ := br.Peek(4)
+
+	compression := Compressions[strings.ToLower(filepath.Ext(filename))]
+	for _, v := range magics {
+		if bytes.HasPrefix(head, v.magic) {
+			compression = v.name
+			break
+		}
+	}
+
+	switch compression {
+	case "gzip":
+		return gzip.NewReader(br)
+	case "bzip2":
+		return ioutil.NopCloser(bzip2.NewReader(br)), nil
+	case "zstd":
+		decoder, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	}
+
+	return ioutil.NopCloser(br), nil
+}
+
+// trimCompression returns filename without the extension of a compression.
+func trimCompression(filename string) string {
+	ext := filepath.Ext(filename)
+	if _, ok := Compressions[strings.ToLower(ext)]; ok {
+		return strings.TrimSuffix(filename, ext)
+	}
+	return filename
+}
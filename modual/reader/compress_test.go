@@ -0,0 +1,102 @@
+package reader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// bzip2 of "hello\n", compress/bzip2 can only read.
+var bzip2Hello = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xc1, 0xc0, 0x80, 0xe2, 0x00, 0x00,
+	0x01, 0x41, 0x00, 0x00, 0x10, 0x02, 0x44, 0xa0, 0x00, 0x30, 0xcd, 0x00, 0xc3, 0x46, 0x29, 0x97,
+	0x17, 0x72, 0x45, 0x38, 0x50, 0x90, 0xc1, 0xc0, 0x80, 0xe2,
+}
+
+func gzipData(t *testing.T, data string) []byte {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Write([]byte(data))
+	err := writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zstdData(t *testing.T, data string) []byte {
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer encoder.Close()
+	return encoder.EncodeAll([]byte(data), nil)
+}
+
+func TestDecompress(t *testing.T) {
+	gz := gzipData(t, "hello\n")
+
+	tests := []struct {
+		name     string
+		filename string
+		input    []byte
+		want     string
+	}{
+		{"plain", "a.tsv", []byte("hello\n"), "hello\n"},
+		{"short plain", "a.tsv", []byte("h"), "h"},
+		{"gzip", "a.tsv.gz", gz, "hello\n"},
+		{"gzip without extension", "a.tsv", gz, "hello\n"},
+		{"gzip members", "a.gz", append(append([]byte(nil), gz...), gzipData(t, "world\n")...), "hello\nworld\n"},
+		{"bzip2", "a.tsv.bz2", bzip2Hello, "hello\n"},
+		{"zstd", "a.tsv.zst", zstdData(t, "hello\n"), "hello\n"},
+		{"zstd without extension", "a", zstdData(t, "hello\n"), "hello\n"},
+	}
+
+	for _, v := range tests {
+		reader, err := Decompress(bytes.NewReader(v.input), v.filename)
+		if err != nil {
+			t.Errorf("%s: %v", v.name, err)
+			continue
+		}
+
+		data, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Errorf("%s: %v", v.name, err)
+			continue
+		}
+
+		if string(data) != v.want {
+			t.Errorf("%s: got %q, want %q", v.name, data, v.want)
+		}
+	}
+}
+
+func TestDecompressCorrupt(t *testing.T) {
+	_, err := Decompress(bytes.NewReader([]byte("not gzip")), "a.tsv.gz")
+	if err == nil {
+		t.Errorf("got no error for a .gz file without gzip header")
+	}
+}
+
+func TestTrimCompression(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"ideas.jsonl.gz", "ideas.jsonl"},
+		{"ideas.jsonl.GZ", "ideas.jsonl"},
+		{"ideas.tsv.zst", "ideas.tsv"},
+		{"ideas.tsv", "ideas.tsv"},
+		{"ideas", "ideas"},
+	}
+
+	for _, v := range tests {
+		if filename := trimCompression(v.filename); filename != v.want {
+			t.Errorf("trimCompression(%q) = %q, want %q", v.filename, filename, v.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package reader
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// CsvDecoder reads RFC 4180 records: fields can be quoted with '"', quoted
+// fields can contain the delimiter and line breaks, and '""' in a quoted
+// field is a quote. The delimiter is Schema.Delimiter or ','. The raw text
+// of a record is kept as read from the input, also when it is malformed.
+type CsvDecoder struct {
+	cr     *csv.Reader
+	input  *csvInput
+	offset int64
+}
+
+// csvInput keeps the bytes read from the input that are not yet returned as
+// the raw text of a record.
+type csvInput struct {
+	reader io.Reader
+	buf    []byte
+}
+
+func (this *csvInput) Read(p []byte) (int, error) {
+	n, err := this.reader.Read(p)
+	this.buf = append(this.buf, p[:n]...)
+	return n, err
+}
+
+func NewCsvDecoder(input io.Reader, schema Schema) Decoder {
+	delimiter := schema.Delimiter
+	if delimiter == "" {
+		delimiter = ","
+	}
+
+	tee := &csvInput{reader: input}
+	cr := csv.NewReader(tee)
+	cr.Comma, _ = utf8.DecodeRuneInString(delimiter)
+	cr.FieldsPerRecord = -1
+
+	return &CsvDecoder{cr: cr, input: tee}
+}
+
+func (this *CsvDecoder) Decode() (*Record, error) {
+	fields, err := this.cr.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	} else if e, ok := err.(*csv.ParseError); ok {
+		return nil, &RejectError{Line: e.StartLine, Raw: this.raw(), Reason: "malformed csv"}
+	} else if err != nil {
+		return nil, err
+	}
+
+	line, _ := this.cr.FieldPos(0)
+
+	return &Record{Line: line, Raw: this.raw(), Fields: fields}, nil
+}
+
+// raw returns the text of the record read last, without the line break.
+func (this *CsvDecoder) raw() string {
+	// csv.Reader预读了输入, 按读到的位置截取该记录的原文
+	offset := this.cr.InputOffset()
+	n := int(offset - this.offset)
+	if n > len(this.input.buf) {
+		n = len(this.input.buf)
+	}
+
+	raw := string(this.input.buf[:n])
+	this.input.buf = this.input.buf[n:]
+	this.offset = offset
+
+	return strings.TrimRight(raw, "\r\n")
+}
+
+func init() {
+	Register("csv", NewCsvDecoder)
+}
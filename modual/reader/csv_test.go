@@ -0,0 +1,106 @@
+package reader
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// decodeAll returns the records and rejects decoder reads before io.EOF or
+// another error.
+func decodeAll(decoder Decoder) ([]Record, []RejectError, error) {
+	var records []Record
+	var rejects []RejectError
+	for {
+		record, err := decoder.Decode()
+		if err == io.EOF {
+			return records, rejects, nil
+		} else if e, ok := err.(*RejectError); ok {
+			rejects = append(rejects, *e)
+			continue
+		} else if err != nil {
+			return records, rejects, err
+		}
+
+		records = append(records, *record)
+	}
+}
+
+func TestTsvDecoder(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Record
+	}{
+		{"empty", "", nil},
+		{"lines", "a\tb\n1\t2\n", []Record{
+			{Line: 1, Raw: "a\tb", Fields: []string{"a", "b"}},
+			{Line: 2, Raw: "1\t2", Fields: []string{"1", "2"}},
+		}},
+		{"no final line break", "a\tb\r\n1\t\"2\"", []Record{
+			{Line: 1, Raw: "a\tb", Fields: []string{"a", "b"}},
+			{Line: 2, Raw: "1\t\"2\"", Fields: []string{"1", "\"2\""}},
+		}},
+		{"empty fields", "\t\n", []Record{
+			{Line: 1, Raw: "\t", Fields: []string{"", ""}},
+		}},
+	}
+
+	for _, v := range tests {
+		records, rejects, err := decodeAll(NewTsvDecoder(strings.NewReader(v.input), Schema{}))
+		if err != nil || rejects != nil {
+			t.Errorf("%s: got error %v and rejects %v", v.name, err, rejects)
+			continue
+		}
+
+		if !reflect.DeepEqual(records, v.want) {
+			t.Errorf("%s: got %+v, want %+v", v.name, records, v.want)
+		}
+	}
+}
+
+func TestCsvDecoder(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		delimiter string
+		want      []Record
+		rejects   []RejectError
+	}{
+		{"empty", "", "", nil, nil},
+		{"quoted", "a,\"b,c\"\n1,\"say \"\"hi\"\"\"\n", "", []Record{
+			{Line: 1, Raw: "a,\"b,c\"", Fields: []string{"a", "b,c"}},
+			{Line: 2, Raw: "1,\"say \"\"hi\"\"\"", Fields: []string{"1", "say \"hi\""}},
+		}, nil},
+		{"line break in field", "1,\"x\r\ny\"\r\n2,z", "", []Record{
+			{Line: 1, Raw: "1,\"x\r\ny\"", Fields: []string{"1", "x\ny"}},
+			{Line: 3, Raw: "2,z", Fields: []string{"2", "z"}},
+		}, nil},
+		{"delimiter", "1;\"a;b\"\n", ";", []Record{
+			{Line: 1, Raw: "1;\"a;b\"", Fields: []string{"1", "a;b"}},
+		}, nil},
+		{"malformed", "1,a\n2,\"b\"c,3\n3, c \n", "", []Record{
+			{Line: 1, Raw: "1,a", Fields: []string{"1", "a"}},
+			{Line: 3, Raw: "3, c ", Fields: []string{"3", " c "}},
+		}, []RejectError{
+			{Line: 2, Raw: "2,\"b\"c,3", Reason: "malformed csv"},
+		}},
+	}
+
+	for _, v := range tests {
+		records, rejects, err := decodeAll(NewCsvDecoder(strings.NewReader(v.input), Schema{Delimiter: v.delimiter}))
+		if err != nil {
+			t.Errorf("%s: %v", v.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(records, v.want) {
+			t.Errorf("%s: got %+v, want %+v", v.name, records, v.want)
+		}
+
+		if !reflect.DeepEqual(rejects, v.rejects) {
+			t.Errorf("%s: got rejects %+v, want %+v", v.name, rejects, v.rejects)
+		}
+	}
+}
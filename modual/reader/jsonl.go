@@ -0,0 +1,106 @@
+package reader
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JsonlDecoder reads a json object from every line and returns the values
+// at the paths of the columns, in the order of the columns. A path like
+// "user::id" looks up the field id of the object in the field user. Missing
+// and null values are empty, objects and arrays are returned as json.
+type JsonlDecoder struct {
+	br    *bufio.Reader
+	line  int
+	paths [][]string
+}
+
+func NewJsonlDecoder(input io.Reader, schema Schema) Decoder {
+	var paths [][]string
+	for _, v := range schema.Columns {
+		path := v.Path
+		if path == "" {
+			path = v.Name
+		}
+		paths = append(paths, strings.Split(path, "::"))
+	}
+
+	return &JsonlDecoder{br: bufio.NewReader(input), paths: paths}
+}
+
+// NamedFields reports that the fields are returned in the order of the
+// schema.
+func (this *JsonlDecoder) NamedFields() bool {
+	return true
+}
+
+func (this *JsonlDecoder) Decode() (*Record, error) {
+	for {
+		line, err := this.br.ReadString('\n')
+		if err == io.EOF && line == "" {
+			return nil, io.EOF
+		} else if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		this.line += 1
+		line = strings.TrimRight(line, "\r\n")
+
+		// 跳过空行
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		decoder := json.NewDecoder(strings.NewReader(line))
+		decoder.UseNumber()
+
+		var data map[string]interface{}
+		err = decoder.Decode(&data)
+		if err != nil || data == nil {
+			return nil, &RejectError{Line: this.line, Raw: line, Reason: "malformed json"}
+		}
+
+		fields := make([]string, len(this.paths))
+		for i, v := range this.paths {
+			fields[i] = this.lookup(data, v)
+		}
+
+		return &Record{Line: this.line, Raw: line, Fields: fields}, nil
+	}
+}
+
+// lookup returns the value at path in data as a string.
+func (this *JsonlDecoder) lookup(data map[string]interface{}, path []string) string {
+	var value interface{} = data
+	for _, key := range path {
+		v, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+
+		if value, ok = v[key]; !ok {
+			return ""
+		}
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return fmt.Sprint(v)
+	}
+
+	raw, _ := json.Marshal(value)
+	return string(raw)
+}
+
+func init() {
+	Register("jsonl", NewJsonlDecoder)
+}
@@ -0,0 +1,54 @@
+package reader
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJsonlDecoder(t *testing.T) {
+	schema := Schema{Columns: []Column{
+		{Name: "id"},
+		{Name: "user_id", Path: "user::id"},
+		{Name: "tags"},
+	}}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    []Record
+		rejects []RejectError
+	}{
+		{"empty", "", nil, nil},
+		{"fields", `{"id":1,"user":{"id":12345678901234567},"tags":["a","b"]}` + "\n", []Record{
+			{Line: 1, Raw: `{"id":1,"user":{"id":12345678901234567},"tags":["a","b"]}`, Fields: []string{"1", "12345678901234567", `["a","b"]`}},
+		}, nil},
+		{"missing and null", "\n{\"id\":\"x\",\"user\":null,\"tags\":true}\r\n  \n{}", []Record{
+			{Line: 2, Raw: `{"id":"x","user":null,"tags":true}`, Fields: []string{"x", "", "true"}},
+			{Line: 4, Raw: `{}`, Fields: []string{"", "", ""}},
+		}, nil},
+		{"malformed", "{\"id\":1\n[1]\nnull\n{\"id\":2}\n", []Record{
+			{Line: 4, Raw: `{"id":2}`, Fields: []string{"2", "", ""}},
+		}, []RejectError{
+			{Line: 1, Raw: `{"id":1`, Reason: "malformed json"},
+			{Line: 2, Raw: `[1]`, Reason: "malformed json"},
+			{Line: 3, Raw: `null`, Reason: "malformed json"},
+		}},
+	}
+
+	for _, v := range tests {
+		records, rejects, err := decodeAll(NewJsonlDecoder(strings.NewReader(v.input), schema))
+		if err != nil {
+			t.Errorf("%s: %v", v.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(records, v.want) {
+			t.Errorf("%s: got %+v, want %+v", v.name, records, v.want)
+		}
+
+		if !reflect.DeepEqual(rejects, v.rejects) {
+			t.Errorf("%s: got rejects %+v, want %+v", v.name, rejects, v.rejects)
+		}
+	}
+}
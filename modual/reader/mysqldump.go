@@ -0,0 +1,303 @@
+package reader
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	createTableRegexp = regexp.MustCompile("(?i)^CREATE TABLE (?:IF NOT EXISTS )?(?:`[^`]*`\\.)?`([^`]*)`")
+	columnRegexp      = regexp.MustCompile("^\\s+`([^`]*)`\\s")
+	insertRegexp      = regexp.MustCompile("(?i)^(?:INSERT|REPLACE)(?: IGNORE)? INTO (?:`[^`]*`\\.)?`([^`]*)`\\s*(?:\\(([^)]*)\\))?\\s*VALUES\\s*")
+)
+
+// MysqldumpDecoder reads the rows of the table Schema.Table from the INSERT
+// statements of a mysqldump file. The columns of the table are taken from
+// its CREATE TABLE statement or the column list of the INSERT statement, and
+// the fields are returned in the order of Schema.Columns, each looked up by
+// its Path or Name. An INSERT statement whose columns are not known, or that
+// can not be parsed, is an error, since none of its rows can be read. String
+// values are unescaped, NULL is empty.
+type MysqldumpDecoder struct {
+	br      *bufio.Reader
+	line    int
+	table   string
+	names   []string
+	columns []string
+	pending []mysqldumpRow
+}
+
+// mysqldumpRow is a row of an INSERT statement, or the reason it is
+// rejected.
+type mysqldumpRow struct {
+	record *Record
+	reason string
+}
+
+func NewMysqldumpDecoder(input io.Reader, schema Schema) Decoder {
+	var names []string
+	for _, v := range schema.Columns {
+		name := v.Path
+		if name == "" {
+			name = v.Name
+		}
+		names = append(names, strings.ToLower(name))
+	}
+
+	return &MysqldumpDecoder{br: bufio.NewReader(input), table: strings.ToLower(schema.Table), names: names}
+}
+
+// NamedFields reports that the fields are returned in the order of the
+// schema.
+func (this *MysqldumpDecoder) NamedFields() bool {
+	return true
+}
+
+func (this *MysqldumpDecoder) Decode() (*Record, error) {
+	if this.table == "" {
+		return nil, errors.New("mysqldump: schema has no table")
+	}
+
+	for len(this.pending) == 0 {
+		line, err := this.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		if m := createTableRegexp.FindStringSubmatch(line); m != nil && strings.ToLower(m[1]) == this.table {
+			err = this.readColumns()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		m := insertRegexp.FindStringSubmatchIndex(line)
+		if m == nil || strings.ToLower(line[m[2]:m[3]]) != this.table {
+			continue
+		}
+
+		columns := this.columns
+		if m[4] >= 0 {
+			columns = nil
+			for _, v := range strings.Split(line[m[4]:m[5]], ",") {
+				columns = append(columns, strings.ToLower(strings.Trim(strings.TrimSpace(v), "`")))
+			}
+		}
+
+		err = this.parseValues(line[m[1]:], columns)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	row := this.pending[0]
+	this.pending = this.pending[1:]
+	if row.reason != "" {
+		return nil, &RejectError{Line: row.record.Line, Raw: row.record.Raw, Reason: row.reason}
+	}
+
+	return row.record, nil
+}
+
+func (this *MysqldumpDecoder) readLine() (string, error) {
+	line, err := this.br.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return "", io.EOF
+	} else if err != nil && err != io.EOF {
+		return "", err
+	}
+
+	this.line += 1
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readColumns reads the column names of a CREATE TABLE statement.
+func (this *MysqldumpDecoder) readColumns() error {
+	this.columns = nil
+	for {
+		line, err := this.readLine()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		m := columnRegexp.FindStringSubmatch(line)
+		if m == nil {
+			if strings.HasPrefix(line, ")") {
+				return nil
+			}
+			continue
+		}
+
+		this.columns = append(this.columns, strings.ToLower(m[1]))
+	}
+}
+
+// parseValues adds the rows of the VALUES list values to the pending
+// records.
+func (this *MysqldumpDecoder) parseValues(values string, columns []string) error {
+	// 没有列名时无法确定各列的位置
+	if columns == nil {
+		return fmt.Errorf("mysqldump: line %d: no CREATE TABLE statement or column list for table %s", this.line, this.table)
+	}
+
+	// 表的列与列定义的对应关系
+	var positions []int
+	for _, name := range this.names {
+		position := -1
+		for i, v := range columns {
+			if v == name {
+				position = i
+				break
+			}
+		}
+
+		if position < 0 {
+			return fmt.Errorf("mysqldump: table %s has no column %q", this.table, name)
+		}
+		positions = append(positions, position)
+	}
+
+	var rows []mysqldumpRow
+	i := 0
+	for i < len(values) {
+		switch values[i] {
+		case ' ', ',':
+			i += 1
+			continue
+		case ';':
+			i = len(values)
+			continue
+		case '(':
+		default:
+			return fmt.Errorf("mysqldump: line %d: malformed INSERT statement for table %s", this.line, this.table)
+		}
+
+		start := i
+		row, n, ok := parseRow(values[i:])
+		if !ok {
+			return fmt.Errorf("mysqldump: line %d: malformed INSERT statement for table %s", this.line, this.table)
+		}
+		i += n
+
+		record := &Record{Line: this.line, Raw: values[start:i]}
+		if len(row) == len(columns) {
+			record.Fields = make([]string, len(positions))
+			for j, v := range positions {
+				record.Fields[j] = row[v]
+			}
+		} else {
+			rows = append(rows, mysqldumpRow{record: record, reason: "field count"})
+			continue
+		}
+
+		rows = append(rows, mysqldumpRow{record: record})
+	}
+
+	this.pending = append(this.pending, rows...)
+	return nil
+}
+
+// parseRow parses the row "(v1,v2,...)" at the start of s and returns its
+// values and length.
+func parseRow(s string) ([]string, int, bool) {
+	var row []string
+	i := 1
+	for {
+		if i >= len(s) {
+			return nil, 0, false
+		}
+
+		var value string
+		var n int
+		var ok bool
+		if s[i] == '\'' || strings.HasPrefix(s[i:], "_binary '") || strings.HasPrefix(s[i:], "_utf8mb4 '") {
+			quote := strings.IndexByte(s[i:], '\'')
+			value, n, ok = parseString(s[i+quote:])
+			n += quote
+		} else {
+			end := strings.IndexAny(s[i:], ",)")
+			if end < 0 {
+				return nil, 0, false
+			}
+			value, n, ok = strings.TrimSpace(s[i:i+end]), end, true
+			if strings.EqualFold(value, "NULL") {
+				value = ""
+			}
+		}
+
+		if !ok {
+			return nil, 0, false
+		}
+
+		row = append(row, value)
+		i += n
+
+		if i >= len(s) {
+			return nil, 0, false
+		}
+
+		switch s[i] {
+		case ',':
+			i += 1
+		case ')':
+			return row, i + 1, true
+		default:
+			return nil, 0, false
+		}
+	}
+}
+
+// parseString parses the quoted SQL string at the start of s and returns
+// its unescaped value and length.
+func parseString(s string) (string, int, bool) {
+	var value strings.Builder
+	i := 1
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			switch e := s[i+1]; e {
+			case '0':
+				value.WriteByte(0)
+			case 'b':
+				value.WriteByte('\b')
+			case 'n':
+				value.WriteByte('\n')
+			case 'r':
+				value.WriteByte('\r')
+			case 't':
+				value.WriteByte('\t')
+			case 'Z':
+				value.WriteByte(0x1a)
+			case '%', '_':
+				value.WriteByte('\\')
+				value.WriteByte(e)
+			default:
+				value.WriteByte(e)
+			}
+			i += 2
+		case c == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			value.WriteByte('\'')
+			i += 2
+		case c == '\'':
+			return value.String(), i + 1, true
+		default:
+			value.WriteByte(c)
+			i += 1
+		}
+	}
+
+	return "", 0, false
+}
+
+func init() {
+	Register("mysqldump", NewMysqldumpDecoder)
+}
@@ -0,0 +1,75 @@
+package reader
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMysqldumpDecoder(t *testing.T) {
+	schema := Schema{Table: "projects", Columns: []Column{
+		{Name: "id"},
+		{Name: "name", Path: "title"},
+	}}
+
+	create := "CREATE TABLE `projects` (\n  `id` int NOT NULL,\n  `user_id` int,\n  `title` varchar(255),\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB;\n"
+
+	tests := []struct {
+		name    string
+		input   string
+		want    []Record
+		rejects []RejectError
+		err     bool
+	}{
+		{"empty", "", nil, nil, false},
+		{"create table", create +
+			"INSERT INTO `other` VALUES (1,2,'x');\n" +
+			"INSERT INTO `projects` VALUES (1,10,'Alpha \\'x\\''),(2,11,'a,b)'),(3,NULL,NULL);\n", []Record{
+			{Line: 8, Raw: "(1,10,'Alpha \\'x\\'')", Fields: []string{"1", "Alpha 'x'"}},
+			{Line: 8, Raw: "(2,11,'a,b)')", Fields: []string{"2", "a,b)"}},
+			{Line: 8, Raw: "(3,NULL,NULL)", Fields: []string{"3", ""}},
+		}, nil, false},
+		{"column list", "INSERT INTO `db`.`Projects` (`title`, `id`) VALUES ('It''s',4),(_binary 'b\\0\\n',5);\n", []Record{
+			{Line: 1, Raw: "('It''s',4)", Fields: []string{"4", "It's"}},
+			{Line: 1, Raw: "(_binary 'b\\0\\n',5)", Fields: []string{"5", "b\x00\n"}},
+		}, nil, false},
+		{"field count", create + "INSERT INTO `projects` VALUES (1,10,'a'),(2,'b'),(3,12,'c');\n", []Record{
+			{Line: 7, Raw: "(1,10,'a')", Fields: []string{"1", "a"}},
+			{Line: 7, Raw: "(3,12,'c')", Fields: []string{"3", "c"}},
+		}, []RejectError{
+			{Line: 7, Raw: "(2,'b')", Reason: "field count"},
+		}, false},
+		{"no columns", "INSERT INTO `projects` VALUES (1,10,'a');\n", nil, nil, true},
+		{"missing column", "INSERT INTO `projects` (`id`) VALUES (1);\n", nil, nil, true},
+		{"malformed", create + "INSERT INTO `projects` VALUES (1,10,'a),(2,11,'b');\n", nil, nil, true},
+	}
+
+	for _, v := range tests {
+		records, rejects, err := decodeAll(NewMysqldumpDecoder(strings.NewReader(v.input), schema))
+		if v.err {
+			if err == nil {
+				t.Errorf("%s: got %+v, want an error", v.name, records)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", v.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(records, v.want) {
+			t.Errorf("%s: got %+v, want %+v", v.name, records, v.want)
+		}
+
+		if !reflect.DeepEqual(rejects, v.rejects) {
+			t.Errorf("%s: got rejects %+v, want %+v", v.name, rejects, v.rejects)
+		}
+	}
+}
+
+func TestMysqldumpDecoderNoTable(t *testing.T) {
+	decoder := NewMysqldumpDecoder(strings.NewReader("INSERT INTO `projects` (`id`) VALUES (1);\n"), Schema{Columns: []Column{{Name: "id"}}})
+	if _, _, err := decodeAll(decoder); err == nil {
+		t.Errorf("got no error for a schema without table")
+	}
+}
@@ -0,0 +1,206 @@
+package reader
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// binding maps a column of the input file to a field of the record.
+type binding struct {
+	field    int
+	column   int
+	name     string
+	kind     string
+	required bool
+}
+
+// part reads the records of one input file.
+type part struct {
+	file       *os.File
+	body       io.ReadCloser
+	decoder    Decoder
+	columns    int
+	recordType reflect.Type
+	bindings   []binding
+	times      *timeParser
+}
+
+func openPart(filename string, schema Schema, recordType reflect.Type, times *timeParser) (*part, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := Decompress(file, filename)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("reader: %s: %v", filename, err)
+	}
+
+	format := schema.FileFormat(filename)
+	this := &part{
+		file:       file,
+		body:       body,
+		decoder:    decoders[format](body, schema),
+		columns:    len(schema.Columns),
+		recordType: recordType,
+		times:      times,
+	}
+
+	positions := make(map[string]int)
+	for i, v := range schema.Columns {
+		positions[v.Name] = i
+	}
+
+	// 根据列名行确定各列的位置, jsonl和mysqldump格式的字段总是按列定义的顺序排列
+	named, ok := this.decoder.(NamedDecoder)
+	if schema.Header && !(ok && named.NamedFields()) {
+		var header []string
+		record, err := this.decoder.Decode()
+		if err == nil {
+			header = record.Fields
+		} else if err != io.EOF {
+			this.close()
+			return nil, fmt.Errorf("reader: %s: %v", filename, err)
+		}
+
+		positions = make(map[string]int)
+		for i, v := range header {
+			positions[strings.ToLower(strings.TrimSpace(v))] = i
+		}
+		this.columns = len(header)
+	}
+
+	err = this.bind(schema, positions)
+	if err != nil {
+		this.close()
+		return nil, fmt.Errorf("%v in %s", err, filename)
+	}
+
+	return this, nil
+}
+
+func (this *part) bind(schema Schema, positions map[string]int) error {
+	types := make(map[string]string)
+	for _, v := range schema.Columns {
+		types[v.Name] = v.Type
+	}
+
+	s := this.recordType.Elem()
+	for i := 0; i < s.NumField(); i++ {
+		tag := s.Field(i).Tag.Get("reader")
+		if tag == "" {
+			continue
+		}
+
+		options := strings.Split(tag, ",")
+		name := options[0]
+		required := len(options) > 1 && options[1] == "required"
+
+		kind, ok := types[name]
+		if !ok {
+			if required {
+				return fmt.Errorf("reader: schema has no column %q", name)
+			}
+			continue
+		}
+
+		position, ok := positions[name]
+		if !ok {
+			if required {
+				return fmt.Errorf("reader: header has no column %q", name)
+			}
+			continue
+		}
+
+		var fieldKind reflect.Kind
+		switch kind {
+		case TypeInt, TypeTime:
+			fieldKind = reflect.Int64
+		case TypeFloat:
+			fieldKind = reflect.Float64
+		case TypeString:
+			fieldKind = reflect.String
+		}
+
+		if s.Field(i).Type.Kind() != fieldKind {
+			return fmt.Errorf("reader: column %q of type %s can not be read into %s field %s", name, kind, s.Field(i).Type, s.Field(i).Name)
+		}
+
+		this.bindings = append(this.bindings, binding{field: i, column: position, name: name, kind: kind, required: required})
+	}
+
+	return nil
+}
+
+// next returns a new record of the next line, or a *RejectError if the line
+// does not fit the schema.
+func (this *part) next() (reflect.Value, error) {
+	data, err := this.decoder.Decode()
+	if reject, ok := err.(*RejectError); ok {
+		reject.File = this.file.Name()
+		return reflect.Value{}, reject
+	} else if err != nil {
+		return reflect.Value{}, err
+	}
+
+	record := reflect.New(this.recordType.Elem())
+	if reason := this.parse(data.Fields, record.Elem()); reason != "" {
+		return reflect.Value{}, &RejectError{File: this.file.Name(), Line: data.Line, Raw: data.Raw, Reason: reason}
+	}
+
+	return record, nil
+}
+
+// parse fills record from fields and returns why they do not fit the
+// schema, or "" if they do.
+func (this *part) parse(fields []string, record reflect.Value) string {
+	if len(fields) != this.columns {
+		return "field count"
+	}
+
+	for _, v := range this.bindings {
+		value := fields[v.column]
+		if value == "" {
+			if v.required {
+				return "empty " + v.name
+			}
+			continue
+		}
+
+		field := record.Field(v.field)
+		switch v.kind {
+		case TypeInt:
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return "invalid " + v.name
+			}
+			field.SetInt(n)
+		case TypeFloat:
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return "invalid " + v.name
+			}
+			field.SetFloat(f)
+		case TypeTime:
+			t, ok := this.times.parse(value)
+			if !ok {
+				return "invalid " + v.name
+			}
+			field.SetInt(t)
+		case TypeString:
+			field.SetString(strings.ToLower(value))
+		}
+	}
+
+	return ""
+}
+
+func (this *part) close() {
+	this.body.Close()
+	this.file.Close()
+}
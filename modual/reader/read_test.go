@@ -0,0 +1,230 @@
+package reader_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"doraemon/model"
+	"doraemon/modual/reader"
+)
+
+var projectColumns = []reader.Column{
+	{Name: "id", Type: reader.TypeInt},
+	{Name: "title", Type: reader.TypeString},
+	{Name: "user_id", Type: reader.TypeInt},
+	{Name: "created_at", Type: reader.TypeTime},
+}
+
+// writeFiles writes the named files into a new directory and returns it.
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "reader")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for k, v := range files {
+		err = ioutil.WriteFile(filepath.Join(dir, k), []byte(v), 0644)
+		if err != nil {
+			os.RemoveAll(dir)
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+// readAll reads all records of path like record.
+func readAll(path string, schema reader.Schema, record interface{}) ([]interface{}, reader.Stats, error) {
+	input, err := reader.Open(path, schema, record)
+	if err != nil {
+		return nil, reader.Stats{}, err
+	}
+	defer input.Close()
+
+	var records []interface{}
+	for {
+		v := reflect.New(reflect.TypeOf(record).Elem()).Interface()
+		err = input.Read(v)
+		if err == io.EOF {
+			return records, input.Stats(), nil
+		} else if err != nil {
+			return records, input.Stats(), err
+		}
+
+		records = append(records, reflect.ValueOf(v).Elem().Interface())
+	}
+}
+
+func TestReadProjects(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"projects.tsv": "1\tAlpha\t10\t2013-01-01 10:00:00\n" +
+			"2\tBeta\t11\n" +
+			"x\tGamma\t12\t2013-01-01 10:00:00\n" +
+			"4\t\t13\t2013-01-01 10:00:00\n" +
+			"5\tEpsilon\t14\tyesterday\n" +
+			"6\tZeta\t15\t2013-01-02 10:00:00\n",
+		"header.csv": "Created_At,title,extra,ID,user_id\n" +
+			"2013-01-01 10:00:00,\"Alpha, the first\",x,1,10\n" +
+			"2013-01-02 10:00:00,Beta,,2,11\n",
+		"noheader.csv": "id,title\n1,Alpha\n",
+		"a.tsv":        "1\ta\t10\t2013-01-01 10:00:00\n2\tb\t11\t2013-01-01 10:00:00\n",
+		"b.tsv":        "3\tc\t12\t2013-01-01 10:00:00\n",
+		"c.tsv":        "",
+		"d.tsv":        "4\td\t13\t2013-01-01 10:00:00\n",
+	})
+	defer os.RemoveAll(dir)
+
+	path := func(names ...string) string {
+		for i, v := range names {
+			names[i] = filepath.Join(dir, v)
+		}
+		return strings.Join(names, string(filepath.ListSeparator))
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		schema  reader.Schema
+		want    []model.ProjectRecord
+		rejects map[string]int64
+		err     string
+	}{
+		{
+			name:   "tsv",
+			path:   path("projects.tsv"),
+			schema: reader.Schema{Columns: projectColumns},
+			want: []model.ProjectRecord{
+				{Id: 1, Title: "alpha", UserId: 10, CreatedAt: 1357034400},
+				{Id: 6, Title: "zeta", UserId: 15, CreatedAt: 1357120800},
+			},
+			rejects: map[string]int64{"field count": 1, "invalid id": 1, "empty title": 1, "invalid created_at": 1},
+		},
+		{
+			name:   "csv with header",
+			path:   path("header.csv"),
+			schema: reader.Schema{Format: "csv", Header: true, Columns: projectColumns},
+			want: []model.ProjectRecord{
+				{Id: 1, Title: "alpha, the first", UserId: 10, CreatedAt: 1357034400},
+				{Id: 2, Title: "beta", UserId: 11, CreatedAt: 1357120800},
+			},
+			rejects: map[string]int64{},
+		},
+		{
+			name: "ordered files",
+			path: path("a.tsv", "b.tsv", "c.tsv", "d.tsv"),
+			// 多个文件并行读取, 记录按文件顺序返回
+			schema: reader.Schema{Columns: projectColumns},
+			want: []model.ProjectRecord{
+				{Id: 1, Title: "a", UserId: 10, CreatedAt: 1357034400},
+				{Id: 2, Title: "b", UserId: 11, CreatedAt: 1357034400},
+				{Id: 3, Title: "c", UserId: 12, CreatedAt: 1357034400},
+				{Id: 4, Title: "d", UserId: 13, CreatedAt: 1357034400},
+			},
+			rejects: map[string]int64{},
+		},
+		{
+			name:   "header without a required column",
+			path:   path("noheader.csv"),
+			schema: reader.Schema{Format: "csv", Header: true, Columns: projectColumns},
+			err:    `header has no column "user_id"`,
+		},
+		{
+			name:   "schema without a required column",
+			path:   path("projects.tsv"),
+			schema: reader.Schema{Columns: projectColumns[:3]},
+			err:    `schema has no column "created_at"`,
+		},
+		{
+			name: "type mismatch",
+			path: path("projects.tsv"),
+			schema: reader.Schema{Columns: []reader.Column{
+				{Name: "id", Type: reader.TypeInt},
+				{Name: "title", Type: reader.TypeFloat},
+				{Name: "user_id", Type: reader.TypeInt},
+				{Name: "created_at", Type: reader.TypeTime},
+			}},
+			err: `column "title" of type float can not be read into string field Title`,
+		},
+	}
+
+	for _, v := range tests {
+		records, stats, err := readAll(v.path, v.schema, &model.ProjectRecord{})
+		if v.err != "" {
+			if err == nil || !strings.Contains(err.Error(), v.err) {
+				t.Errorf("%s: got error %v, want %q", v.name, err, v.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", v.name, err)
+			continue
+		}
+
+		var got []model.ProjectRecord
+		for _, record := range records {
+			got = append(got, record.(model.ProjectRecord))
+		}
+
+		if !reflect.DeepEqual(got, v.want) {
+			t.Errorf("%s: got %+v, want %+v", v.name, got, v.want)
+		}
+
+		if stats.Records != int64(len(v.want)) || !reflect.DeepEqual(stats.Rejects, v.rejects) {
+			t.Errorf("%s: got stats %+v, want %d records and rejects %v", v.name, stats, len(v.want), v.rejects)
+		}
+	}
+}
+
+func TestReadOptionalColumns(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"ideas.tsv": "\t1\t10\t2013-01-01 10:00:00\n2\t1\t\t2013-01-01 10:00:00\n",
+	})
+	defer os.RemoveAll(dir)
+
+	// id不是必需的列, 为空时取零值; user_id是必需的列
+	schema := reader.Schema{Columns: []reader.Column{
+		{Name: "id", Type: reader.TypeInt},
+		{Name: "project_id", Type: reader.TypeInt},
+		{Name: "user_id", Type: reader.TypeInt},
+		{Name: "created_at", Type: reader.TypeTime},
+	}}
+
+	records, stats, err := readAll(filepath.Join(dir, "ideas.tsv"), schema, &model.IdeaRecord{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []interface{}{model.IdeaRecord{Id: 0, ProjectId: 1, UserId: 10, CreatedAt: 1357034400}}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("got %+v, want %+v", records, want)
+	}
+
+	if stats.Records != 1 || !reflect.DeepEqual(stats.Rejects, map[string]int64{"empty user_id": 1}) {
+		t.Errorf("got stats %+v", stats)
+	}
+}
+
+func TestReadRecordType(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.tsv": "1\ta\t10\t2013-01-01 10:00:00\n"})
+	defer os.RemoveAll(dir)
+
+	_, err := reader.Open(filepath.Join(dir, "a.tsv"), reader.Schema{Columns: projectColumns}, model.ProjectRecord{})
+	if err == nil {
+		t.Errorf("Open with a struct value got no error")
+	}
+
+	input, err := reader.Open(filepath.Join(dir, "a.tsv"), reader.Schema{Columns: projectColumns}, &model.ProjectRecord{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer input.Close()
+
+	if err = input.Read(&model.IdeaRecord{}); err == nil {
+		t.Errorf("Read of another record type got no error")
+	}
+}
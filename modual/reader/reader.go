@@ -0,0 +1,469 @@
+// Package reader reads the input files of the tasks into typed records, as
+// declared by a Schema.
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	TypeString = "string" // 字符串, 读取时转为小写
+	TypeInt    = "int"    // 整数
+	TypeFloat  = "float"  // 浮点数
+	TypeTime   = "time"   // 时间, 按Schema.Time读取为unix时间戳
+)
+
+// Schema describes the format and the columns of an input file.
+type Schema struct {
+	Format    string     // 文件格式: auto(默认，按扩展名选择), tsv(按制表符分隔), csv(RFC 4180，支持引号), jsonl(每行一个json对象), mysqldump(mysqldump导出的INSERT语句)
+	Delimiter string     // csv格式的分隔符，默认为逗号
+	Header    bool       // 文件首行是否为列名，jsonl和mysqldump格式不使用
+	Table     string     // mysqldump格式读取的表名
+	Time      TimeFormat // 时间列的格式和时区
+	Columns   []Column   // 列定义，无列名行时按文件中的顺序排列
+}
+
+// Column is a named and typed column of an input file.
+type Column struct {
+	Name string // 列名
+	Type string // 列类型: string, int, float, time
+	Path string // jsonl格式中字段的路径，以::分隔嵌套的字段; mysqldump格式中表的列名; 默认为列名
+}
+
+// Extensions are the formats selected by the extension of the input file in
+// auto format. Other files are read as tsv.
+var Extensions = map[string]string{
+	".csv":    "csv",
+	".jsonl":  "jsonl",
+	".ndjson": "jsonl",
+	".sql":    "mysqldump",
+}
+
+// Check reports whether the schema has a known format, a usable time format
+// and columns of known types and unique names.
+func (this Schema) Check() error {
+	if _, ok := decoders[this.Format]; !ok && this.Format != "" && this.Format != "auto" {
+		return fmt.Errorf("reader: unknown format %q", this.Format)
+	}
+
+	if this.Format == "mysqldump" && this.Table == "" {
+		return errors.New("reader: schema has no table for mysqldump format")
+	}
+
+	if utf8.RuneCountInString(this.Delimiter) > 1 {
+		return fmt.Errorf("reader: delimiter %q is not a single character", this.Delimiter)
+	}
+
+	_, err := newTimeParser(this.Time)
+	if err != nil {
+		return err
+	}
+
+	if len(this.Columns) == 0 {
+		return errors.New("reader: schema has no columns")
+	}
+
+	names := make(map[string]bool)
+	for _, v := range this.Columns {
+		if v.Name == "" {
+			return errors.New("reader: schema has a column without name")
+		}
+
+		if names[v.Name] {
+			return fmt.Errorf("reader: schema has duplicate column %q", v.Name)
+		}
+		names[v.Name] = true
+
+		switch v.Type {
+		case TypeString, TypeInt, TypeFloat, TypeTime:
+		default:
+			return fmt.Errorf("reader: column %q has unknown type %q", v.Name, v.Type)
+		}
+	}
+
+	return nil
+}
+
+// FileFormat returns the format filename is read in. The extension of a
+// compression is skipped, so that ideas.jsonl.gz is read as jsonl.
+func (this Schema) FileFormat(filename string) string {
+	if this.Format != "" && this.Format != "auto" {
+		return this.Format
+	}
+
+	if v, ok := Extensions[strings.ToLower(filepath.Ext(trimCompression(filename)))]; ok {
+		return v
+	}
+	return "tsv"
+}
+
+// Record is a record of an input file, split into fields.
+type Record struct {
+	Line   int      // 记录开始的行号
+	Raw    string   // 原始记录
+	Fields []string // 各字段的值
+}
+
+// RejectError is returned by a Decoder for a record it can not split into
+// fields. The Decoder can go on with the next record.
+type RejectError struct {
+	File   string // 文件名, 由Reader填写
+	Line   int
+	Raw    string
+	Reason string
+}
+
+func (this *RejectError) Error() string {
+	return fmt.Sprintf("reader: %s: line %d: %s", this.File, this.Line, this.Reason)
+}
+
+// Stats counts the records a Reader read and rejected.
+type Stats struct {
+	Records int64            // 读取的记录数
+	Rejects map[string]int64 // 各原因跳过的记录数
+}
+
+// Rejected returns the number of rejected records.
+func (this Stats) Rejected() int64 {
+	var rejected int64
+	for _, v := range this.Rejects {
+		rejected += v
+	}
+	return rejected
+}
+
+// RejectRatio returns the share of the rejected records in all records.
+func (this Stats) RejectRatio() float64 {
+	rejected := this.Rejected()
+	if rejected == 0 {
+		return 0
+	}
+	return float64(rejected) / float64(this.Records+rejected)
+}
+
+// Decoder splits an input file into records.
+type Decoder interface {
+	Decode() (*Record, error) // 返回下一条记录，文件结束时返回io.EOF
+}
+
+// NamedDecoder is implemented by a Decoder that finds the columns by name
+// itself and returns the fields in the order of the schema, so the input has
+// no header line.
+type NamedDecoder interface {
+	NamedFields() bool
+}
+
+// NewDecoder creates a Decoder reading input as described by schema.
+type NewDecoder func(input io.Reader, schema Schema) Decoder
+
+var decoders = make(map[string]NewDecoder)
+
+// Register makes a file format available by name.
+// If Register is called twice with the same name or if newDecoder is nil,
+// it panics.
+func Register(name string, newDecoder NewDecoder) {
+	if newDecoder == nil {
+		panic("reader: Register decoder is nil")
+	}
+	if _, dup := decoders[name]; dup {
+		panic("reader: Register called twice for decoder " + name)
+	}
+	decoders[name] = newDecoder
+}
+
+// Reader reads the lines of input files into records of one struct type.
+// The fields of the struct are bound to columns by their tag:
+//
+//	UserId int64 `reader:"user_id,required"`
+//
+// int and time columns go into int64 fields, float columns into float64
+// fields and string columns into string fields. An empty value leaves the
+// field zero, unless the field is required. Lines that do not fit the
+// schema are skipped, counted in Stats and written to the reject log.
+//
+// Several files are read in parallel, but their records are returned in the
+// order of the files.
+type Reader struct {
+	recordType reflect.Type
+	results    []chan partResult
+	current    int
+	done       chan struct{}
+	closeOnce  sync.Once
+	times      *timeParser
+	stats      Stats
+	rejectLog  io.Writer
+}
+
+// partResult is a record of a file, a rejected line or the error that ended
+// reading it.
+type partResult struct {
+	record reflect.Value
+	err    error
+}
+
+// Open opens path for reading records like record, which must be a pointer
+// to a struct, and checks that schema has all columns record needs. path is
+// a list of files, directories and glob patterns, see Files. Compressed
+// files are decompressed while reading.
+func Open(path string, schema Schema, record interface{}) (*Reader, error) {
+	err := schema.Check()
+	if err != nil {
+		return nil, err
+	}
+
+	recordType := reflect.TypeOf(record)
+	if recordType == nil || recordType.Kind() != reflect.Ptr || recordType.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("reader: record must be a pointer to a struct, not %v", recordType)
+	}
+
+	times, err := newTimeParser(schema.Time)
+	if err != nil {
+		return nil, err
+	}
+
+	filenames, err := Files(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按扩展名确定格式后才知道是否需要表名
+	for _, v := range filenames {
+		if schema.FileFormat(v) == "mysqldump" && schema.Table == "" {
+			return nil, fmt.Errorf("reader: schema has no table for mysqldump file %s", v)
+		}
+	}
+
+	// 同步打开第一个文件, 尽早发现列定义和列名行的错误
+	first, err := openPart(filenames[0], schema, recordType, times)
+	if err != nil {
+		return nil, err
+	}
+
+	this := &Reader{
+		recordType: recordType,
+		results:    make([]chan partResult, len(filenames)),
+		done:       make(chan struct{}),
+		times:      times,
+		stats:      Stats{Rejects: make(map[string]int64)},
+	}
+	for i, _ := range this.results {
+		this.results[i] = make(chan partResult, 1024)
+	}
+
+	go this.readParts(first, filenames, schema)
+
+	return this, nil
+}
+
+// Files returns the input files of path, a list of files, directories and
+// glob patterns separated by ':'. The files of directories and patterns are
+// taken in name order, leaving out hidden files and files starting with '_',
+// like the _SUCCESS marker of Hadoop jobs.
+func Files(path string) ([]string, error) {
+	var filenames []string
+	for _, v := range filepath.SplitList(path) {
+		if v == "" {
+			continue
+		}
+
+		var matches []string
+		if strings.ContainsAny(v, "*?[") {
+			var err error
+			matches, err = filepath.Glob(v)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("reader: no files match %q", v)
+			}
+		} else {
+			info, err := os.Stat(v)
+			if err != nil {
+				return nil, err
+			}
+
+			if !info.IsDir() {
+				filenames = append(filenames, v)
+				continue
+			}
+
+			matches = append(matches, v)
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+
+			if !info.IsDir() {
+				if !skipFile(match) {
+					filenames = append(filenames, match)
+				}
+				continue
+			}
+
+			entries, err := ioutil.ReadDir(match)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, entry := range entries {
+				if !entry.IsDir() && !skipFile(entry.Name()) {
+					filenames = append(filenames, filepath.Join(match, entry.Name()))
+				}
+			}
+		}
+	}
+
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf("reader: no input files in %q", path)
+	}
+
+	return filenames, nil
+}
+
+func skipFile(filename string) bool {
+	name := filepath.Base(filename)
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// readParts reads up to runtime.NumCPU() files at a time. A file is started
+// only after the reads of an earlier one are done, so the file Read is
+// waiting for is always being read.
+func (this *Reader) readParts(first *part, filenames []string, schema Schema) {
+	workers := make(chan struct{}, runtime.NumCPU())
+	for i, filename := range filenames {
+		select {
+		case workers <- struct{}{}:
+		case <-this.done:
+			if i == 0 {
+				first.close()
+			}
+			return
+		}
+
+		go func(i int, filename string) {
+			defer func() { <-workers }()
+			defer close(this.results[i])
+
+			p := first
+			if i > 0 {
+				var err error
+				p, err = openPart(filename, schema, this.recordType, this.times)
+				if err != nil {
+					this.send(i, partResult{err: err})
+					return
+				}
+			}
+			defer p.close()
+
+			for {
+				record, err := p.next()
+				if reject, ok := err.(*RejectError); ok {
+					if !this.send(i, partResult{err: reject}) {
+						return
+					}
+					continue
+				} else if err == io.EOF {
+					return
+				} else if err != nil {
+					this.send(i, partResult{err: fmt.Errorf("reader: %s: %v", filename, err)})
+					return
+				}
+
+				if !this.send(i, partResult{record: record}) {
+					return
+				}
+			}
+		}(i, filename)
+	}
+}
+
+// send passes result of the i-th file to Read, unless the reader is closed.
+func (this *Reader) send(i int, result partResult) bool {
+	select {
+	case this.results[i] <- result:
+		return true
+	case <-this.done:
+		return false
+	}
+}
+
+// Read reads the next line that fits the schema into record. It returns
+// io.EOF at the end of the last file.
+func (this *Reader) Read(record interface{}) error {
+	if reflect.TypeOf(record) != this.recordType {
+		return fmt.Errorf("reader: can not read %v records with a reader of %v", reflect.TypeOf(record), this.recordType)
+	}
+
+	for this.current < len(this.results) {
+		result, ok := <-this.results[this.current]
+		if !ok {
+			this.current += 1
+			continue
+		}
+
+		if reject, ok := result.err.(*RejectError); ok {
+			err := this.reject(reject)
+			if err != nil {
+				return err
+			}
+			continue
+		} else if result.err != nil {
+			return result.err
+		}
+
+		this.stats.Records += 1
+		reflect.ValueOf(record).Elem().Set(result.record.Elem())
+		return nil
+	}
+
+	return io.EOF
+}
+
+// reject counts a rejected line and writes it to the reject log.
+func (this *Reader) reject(reject *RejectError) error {
+	this.stats.Rejects[reject.Reason] += 1
+	if this.rejectLog == nil {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(this.rejectLog, "%s\t%d\t%s\t%q\n", reject.File, reject.Line, reject.Reason, reject.Raw)
+	return err
+}
+
+// LogRejects writes every rejected line to w, as a line of
+// "file \t line \t reason \t quoted raw line".
+func (this *Reader) LogRejects(w io.Writer) {
+	this.rejectLog = w
+}
+
+// Stats returns the records read and rejected so far. It is complete once
+// Read returned io.EOF.
+func (this *Reader) Stats() Stats {
+	stats := Stats{Records: this.stats.Records, Rejects: make(map[string]int64)}
+	for k, v := range this.stats.Rejects {
+		stats.Rejects[k] = v
+	}
+	return stats
+}
+
+// Close stops reading and closes the input files.
+func (this *Reader) Close() error {
+	this.closeOnce.Do(func() {
+		close(this.done)
+	})
+	return nil
+}
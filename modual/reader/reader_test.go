@@ -0,0 +1,105 @@
+package reader
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, v := range []string{"a/part-1", "a/part-0", "a/_SUCCESS", "a/.part-0.crc", "a/sub/part-2", "b.tsv", "c.tsv", "d.jsonl"} {
+		name := filepath.Join(dir, v)
+		err = os.MkdirAll(filepath.Dir(name), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ioutil.WriteFile(name, nil, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := func(names ...string) string {
+		for i, v := range names {
+			if v != "" {
+				names[i] = filepath.Join(dir, v)
+			}
+		}
+		return strings.Join(names, string(filepath.ListSeparator))
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want []string
+		err  bool
+	}{
+		{"file", path("b.tsv"), []string{"b.tsv"}, false},
+		{"hidden file named", path("a/_SUCCESS"), []string{"a/_SUCCESS"}, false},
+		{"directory", path("a"), []string{"a/part-0", "a/part-1"}, false},
+		{"glob", path("*.tsv"), []string{"b.tsv", "c.tsv"}, false},
+		{"glob with directory", path("a*"), []string{"a/part-0", "a/part-1"}, false},
+		{"glob of hidden files", path("a/_*"), nil, true},
+		{"list", path("d.jsonl", "a", "", "b.tsv"), []string{"d.jsonl", "a/part-0", "a/part-1", "b.tsv"}, false},
+		{"missing file", path("e.tsv"), nil, true},
+		{"no matches", path("*.csv"), nil, true},
+		{"empty", "", nil, true},
+	}
+
+	for _, v := range tests {
+		filenames, err := Files(v.path)
+		if v.err {
+			if err == nil {
+				t.Errorf("%s: got %v, want an error", v.name, filenames)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", v.name, err)
+			continue
+		}
+
+		var want []string
+		for _, name := range v.want {
+			want = append(want, filepath.Join(dir, name))
+		}
+
+		if !reflect.DeepEqual(filenames, want) {
+			t.Errorf("%s: got %v, want %v", v.name, filenames, want)
+		}
+	}
+}
+
+func TestStatsRejectRatio(t *testing.T) {
+	tests := []struct {
+		name     string
+		stats    Stats
+		rejected int64
+		ratio    float64
+	}{
+		{"empty", Stats{}, 0, 0},
+		{"no rejects", Stats{Records: 10}, 0, 0},
+		{"rejects", Stats{Records: 97, Rejects: map[string]int64{"field count": 1, "invalid id": 2}}, 3, 0.03},
+		{"all rejected", Stats{Rejects: map[string]int64{"field count": 4}}, 4, 1},
+	}
+
+	for _, v := range tests {
+		if rejected := v.stats.Rejected(); rejected != v.rejected {
+			t.Errorf("%s: Rejected() = %d, want %d", v.name, rejected, v.rejected)
+		}
+
+		if ratio := v.stats.RejectRatio(); ratio != v.ratio {
+			t.Errorf("%s: RejectRatio() = %v, want %v", v.name, ratio, v.ratio)
+		}
+	}
+}
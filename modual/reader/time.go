@@ -0,0 +1,88 @@
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// 内置时区数据, 没有zoneinfo的机器上也能加载Asia/Shanghai等时区
+	_ "time/tzdata"
+)
+
+const (
+	LayoutEpoch   = "epoch"    // unix时间戳(秒)
+	LayoutEpochMs = "epoch_ms" // unix时间戳(毫秒)
+	LayoutRFC3339 = "rfc3339"  // RFC 3339, 如2006-01-02T15:04:05+08:00
+)
+
+// DefaultTimeLayout is the layout of the time columns when no layout is
+// configured.
+const DefaultTimeLayout = "2006-01-02 15:04:05"
+
+// TimeFormat describes how the time columns of an input are written.
+type TimeFormat struct {
+	Layouts  []string // 时间格式，按顺序尝试: Go时间格式、epoch、epoch_ms或rfc3339，默认为2006-01-02 15:04:05
+	Location string   // 不带时区的时间所在的时区，如Asia/Shanghai，默认为UTC
+}
+
+// timeParser parses the values of time columns into unix timestamps.
+type timeParser struct {
+	layouts  []string
+	location *time.Location
+}
+
+func newTimeParser(format TimeFormat) (*timeParser, error) {
+	this := &timeParser{layouts: format.Layouts, location: time.UTC}
+	if len(this.layouts) == 0 {
+		this.layouts = []string{DefaultTimeLayout}
+	}
+
+	for _, v := range this.layouts {
+		if v == "" {
+			return nil, errors.New("reader: time format has an empty layout")
+		}
+	}
+
+	if format.Location != "" {
+		location, err := time.LoadLocation(format.Location)
+		if err != nil {
+			return nil, fmt.Errorf("reader: time format has unknown location %q, %v", format.Location, err)
+		}
+		this.location = location
+	}
+
+	return this, nil
+}
+
+// parse returns the unix timestamp of value in the first layout it fits.
+func (this *timeParser) parse(value string) (int64, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range this.layouts {
+		switch strings.ToLower(layout) {
+		case LayoutEpoch:
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err == nil {
+				return n, true
+			}
+		case LayoutEpochMs:
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err == nil {
+				return time.UnixMilli(n).Unix(), true
+			}
+		case LayoutRFC3339:
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err == nil {
+				return t.Unix(), true
+			}
+		default:
+			t, err := time.ParseInLocation(layout, value, this.location)
+			if err == nil {
+				return t.Unix(), true
+			}
+		}
+	}
+
+	return 0, false
+}
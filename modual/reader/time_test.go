@@ -0,0 +1,58 @@
+package reader
+
+import (
+	"testing"
+)
+
+func TestTimeParser(t *testing.T) {
+	tests := []struct {
+		name   string
+		format TimeFormat
+		value  string
+		want   int64
+		ok     bool
+	}{
+		{"default", TimeFormat{}, "2013-01-01 10:00:00", 1357034400, true},
+		{"default trimmed", TimeFormat{}, " 2013-01-01 10:00:00\r", 1357034400, true},
+		{"default wrong layout", TimeFormat{}, "2013-01-01", 0, false},
+		{"location", TimeFormat{Location: "Asia/Shanghai"}, "2013-01-01 10:00:00", 1357034400 - 8*3600, true},
+		{"epoch", TimeFormat{Layouts: []string{LayoutEpoch}}, "1357034400", 1357034400, true},
+		{"epoch ms", TimeFormat{Layouts: []string{LayoutEpochMs}}, "1357034400999", 1357034400, true},
+		{"rfc3339", TimeFormat{Layouts: []string{LayoutRFC3339}, Location: "Asia/Shanghai"}, "2013-01-01T10:00:00Z", 1357034400, true},
+		{"rfc3339 upper case", TimeFormat{Layouts: []string{"RFC3339"}}, "2013-01-01T18:00:00.5+08:00", 1357034400, true},
+		{"layouts in order", TimeFormat{Layouts: []string{"2006-01-02", LayoutEpoch}}, "1357034400", 1357034400, true},
+		{"first layout", TimeFormat{Layouts: []string{"2006-01-02", LayoutEpoch}}, "2013-01-01", 1356998400, true},
+		{"no layout fits", TimeFormat{Layouts: []string{LayoutEpoch, LayoutRFC3339}}, "2013-01-01", 0, false},
+		{"empty", TimeFormat{}, "", 0, false},
+	}
+
+	for _, v := range tests {
+		parser, err := newTimeParser(v.format)
+		if err != nil {
+			t.Errorf("%s: %v", v.name, err)
+			continue
+		}
+
+		value, ok := parser.parse(v.value)
+		if ok != v.ok || value != v.want {
+			t.Errorf("%s: parse(%q) = %v, %v, want %v, %v", v.name, v.value, value, ok, v.want, v.ok)
+		}
+	}
+}
+
+func TestNewTimeParserError(t *testing.T) {
+	tests := []struct {
+		name   string
+		format TimeFormat
+	}{
+		{"empty layout", TimeFormat{Layouts: []string{LayoutEpoch, ""}}},
+		{"unknown location", TimeFormat{Location: "Mars/Olympus"}},
+	}
+
+	for _, v := range tests {
+		_, err := newTimeParser(v.format)
+		if err == nil {
+			t.Errorf("%s: got no error", v.name)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package reader
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// TsvDecoder splits every line at tabs. Fields can not contain tabs or line
+// breaks, and quotes are part of the value.
+type TsvDecoder struct {
+	br   *bufio.Reader
+	line int
+}
+
+func NewTsvDecoder(input io.Reader, schema Schema) Decoder {
+	return &TsvDecoder{br: bufio.NewReader(input)}
+}
+
+func (this *TsvDecoder) Decode() (*Record, error) {
+	line, err := this.br.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return nil, io.EOF
+	} else if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	this.line += 1
+	line = strings.TrimRight(line, "\r\n")
+
+	return &Record{Line: this.line, Raw: line, Fields: strings.Split(line, "\t")}, nil
+}
+
+func init() {
+	Register("tsv", NewTsvDecoder)
+}
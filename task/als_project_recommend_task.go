@@ -0,0 +1,430 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"doraemon/model"
+	"doraemon/util"
+)
+
+func init() {
+	Register("AlsProjectRecommend", NewAlsProjectRecommendTask())
+}
+
+// AlsProjectRecommendTask recommends projects to every user by factorizing
+// the implicit user×project interaction matrix with alternating least
+// squares (Hu, Koren and Volinsky, "Collaborative Filtering for Implicit
+// Feedback Datasets"). Every idea, comment and follow/join of a user in a
+// project is one interaction; r interactions give the preference 1 a
+// confidence of 1+AlsAlpha*r, while all other cells have preference 0 with
+// confidence 1.
+type AlsProjectRecommendTask struct {
+	*ProjectRecommendTask
+	UserProjectCountMap map[int64]map[int64]float64 // 用户在项目中的交互次数
+	ProjectUserCountMap map[int64]map[int64]float64 // 项目中用户的交互次数
+	UserFactorMap       map[int64][]float64         // 用户隐因子
+	ProjectFactorMap    map[int64][]float64         // 项目隐因子
+}
+
+func NewAlsProjectRecommendTask() *AlsProjectRecommendTask {
+	return &AlsProjectRecommendTask{
+		ProjectRecommendTask: NewProjectRecommendTask(),
+		UserProjectCountMap:  make(map[int64]map[int64]float64),
+		ProjectUserCountMap:  make(map[int64]map[int64]float64),
+		UserFactorMap:        make(map[int64][]float64),
+		ProjectFactorMap:     make(map[int64][]float64),
+	}
+}
+
+func (this *AlsProjectRecommendTask) DoResult(result <-chan Result, output *os.File) error {
+	var number int = 0
+	for _ = range result {
+		number += 1
+
+		if number%10000 == 0 {
+			fmt.Printf("%d\t%s\n", number, time.Now().String())
+		}
+	}
+
+	// 输入文件读取失败或跳过的行过多时不生成结果
+	if this.InputErr != nil {
+		return this.InputErr
+	}
+
+	// 汇总用户在项目中的关注/加入、创意、评论次数
+	this.DoCollectCount(this.ProjectUserMap)
+	this.DoCollectCount(this.ProjectIdeaMap)
+	this.DoCollectCount(this.ProjectCommentMap)
+
+	// 训练用户和项目的隐因子
+	err := this.DoTrain()
+	if err != nil {
+		return err
+	}
+
+	// 保存隐因子
+	factorFile := model.GlobalConf.AlsFactorFile
+	if factorFile == "" {
+		factorFile = output.Name() + ".factors"
+	}
+
+	err = this.DoSaveFactors(factorFile)
+	if err != nil {
+		return err
+	}
+
+	// 按id顺序输出, 相同输入的结果相同
+	items := make(map[int64]interface{})
+	projectIds := this.DoSortedIds(this.ProjectUserCountMap)
+	for _, userId := range this.DoSortedIds(this.UserProjectCountMap) {
+		projects := this.UserProjectCountMap[userId]
+		var projectRecommends []model.ProjectRecommend
+		userFactor := this.UserFactorMap[userId]
+		for _, projectId := range projectIds {
+			projectFactor := this.ProjectFactorMap[projectId]
+			// 过滤用户已经参与过的项目, 包括已取消关注或已退出的项目
+			if _, ok := projects[projectId]; ok || this.ProjectFormerUserMap[projectId][userId] {
+				continue
+			}
+
+			var projectRecommend model.ProjectRecommend
+			projectRecommend.Id = projectId
+			projectRecommend.Title = this.ProjectInfoMap[projectId].Title
+			projectRecommend.UserId = this.ProjectInfoMap[projectId].UserId
+			projectRecommend.Score = util.Dot(userFactor, projectFactor)
+
+			projectRecommends = this.DoInsertTop(projectRecommends, projectRecommend)
+		}
+
+		if len(projectRecommends) == 0 {
+			continue
+		}
+
+		var personalProjectRecommend model.PersonalProjectRecommend
+		personalProjectRecommend.UserId = userId
+		personalProjectRecommend.Projects = projectRecommends
+
+		data, err := json.Marshal(&personalProjectRecommend)
+		if err != nil {
+			continue
+		}
+
+		data = append(data, '\n')
+		_, _ = output.WriteString(string(data))
+
+		items[userId] = personalProjectRecommend
+	}
+
+	// 将推荐结果写入memcached
+	return PublishResult("AlsProjectRecommend", nil, items)
+}
+
+func (this *AlsProjectRecommendTask) DoCollectCount(data map[int64]map[int64][]int64) {
+	for projectId, users := range data {
+		// 只推荐项目文件中存在的项目
+		if _, ok := this.ProjectInfoMap[projectId]; !ok {
+			continue
+		}
+
+		for userId, times := range users {
+			if v, ok := this.UserProjectCountMap[userId]; ok {
+				v[projectId] += float64(len(times))
+			} else {
+				subMap := make(map[int64]float64)
+				subMap[projectId] = float64(len(times))
+				this.UserProjectCountMap[userId] = subMap
+			}
+
+			if v, ok := this.ProjectUserCountMap[projectId]; ok {
+				v[userId] += float64(len(times))
+			} else {
+				subMap := make(map[int64]float64)
+				subMap[userId] = float64(len(times))
+				this.ProjectUserCountMap[projectId] = subMap
+			}
+		}
+	}
+}
+
+// DoTrain alternately solves the user factors with the project factors
+// fixed and the project factors with the user factors fixed.
+func (this *AlsProjectRecommendTask) DoTrain() error {
+	factors := model.GlobalConf.AlsFactors
+	if factors < 1 {
+		return errors.New("DoTrain AlsProjectRecommendTask check fail, AlsFactors must be positive")
+	}
+
+	if model.GlobalConf.AlsIterations < 1 {
+		return errors.New("DoTrain AlsProjectRecommendTask check fail, AlsIterations must be positive")
+	}
+
+	if model.GlobalConf.AlsRegularization <= 0 {
+		return errors.New("DoTrain AlsProjectRecommendTask check fail, AlsRegularization must be positive")
+	}
+
+	// 使用固定种子按id顺序初始化隐因子, 保证结果可以复现
+	random := rand.New(rand.NewSource(1))
+	for _, k := range this.DoSortedIds(this.UserProjectCountMap) {
+		this.UserFactorMap[k] = this.DoRandomFactor(random, factors)
+	}
+	for _, k := range this.DoSortedIds(this.ProjectUserCountMap) {
+		this.ProjectFactorMap[k] = this.DoRandomFactor(random, factors)
+	}
+
+	for i := 0; i < model.GlobalConf.AlsIterations; i++ {
+		err := this.DoSolve(this.UserProjectCountMap, this.ProjectFactorMap, this.UserFactorMap)
+		if err != nil {
+			return err
+		}
+
+		err = this.DoSolve(this.ProjectUserCountMap, this.UserFactorMap, this.ProjectFactorMap)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("als iteration %d\t%s\n", i+1, time.Now().String())
+	}
+
+	return nil
+}
+
+// DoSortedIds returns the ids of counts in ascending order. Maps are ranged
+// in random order, so everything the results depend on, like drawing the
+// initial factors and summing floats, goes by id instead.
+func (this *AlsProjectRecommendTask) DoSortedIds(counts map[int64]map[int64]float64) []int64 {
+	ids := make([]int64, 0, len(counts))
+	for k, _ := range counts {
+		ids = append(ids, k)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
+
+func (this *AlsProjectRecommendTask) DoRandomFactor(random *rand.Rand, factors int) []float64 {
+	factor := make([]float64, factors)
+	for i, _ := range factor {
+		factor[i] = random.Float64() * 0.01
+	}
+	return factor
+}
+
+// DoSolve updates the factor of every row of counts given the fixed factors
+// of the columns:
+//
+//	x = (Y'Y + Y'(C-I)Y + λI)^-1 Y'Cp
+//
+// Y'Y is shared by all rows, so every row only adds the columns it has
+// interactions with.
+func (this *AlsProjectRecommendTask) DoSolve(counts map[int64]map[int64]float64, fixed map[int64][]float64, target map[int64][]float64) error {
+	factors := model.GlobalConf.AlsFactors
+	alpha := model.GlobalConf.AlsAlpha
+
+	fixedIds := make([]int64, 0, len(fixed))
+	for k, _ := range fixed {
+		fixedIds = append(fixedIds, k)
+	}
+	sort.Slice(fixedIds, func(i, j int) bool { return fixedIds[i] < fixedIds[j] })
+
+	yty := make([]float64, factors*factors)
+	for _, k := range fixedIds {
+		y := fixed[k]
+		for i := 0; i < factors; i++ {
+			for j := 0; j < factors; j++ {
+				yty[i*factors+j] += y[i] * y[j]
+			}
+		}
+	}
+
+	rows := this.DoSortedIds(counts)
+	solutions := make([][]float64, len(rows))
+
+	workers := runtime.NumCPU()
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			a := make([]float64, factors*factors)
+			b := make([]float64, factors)
+			for r := w; r < len(rows); r += workers {
+				copy(a, yty)
+				for i := 0; i < factors; i++ {
+					a[i*factors+i] += model.GlobalConf.AlsRegularization
+					b[i] = 0
+				}
+
+				cols := make([]int64, 0, len(counts[rows[r]]))
+				for k, _ := range counts[rows[r]] {
+					cols = append(cols, k)
+				}
+				sort.Slice(cols, func(i, j int) bool { return cols[i] < cols[j] })
+
+				for _, col := range cols {
+					y := fixed[col]
+					count := counts[rows[r]][col]
+					confidence := 1 + alpha*count
+					for i := 0; i < factors; i++ {
+						for j := 0; j < factors; j++ {
+							a[i*factors+j] += (confidence - 1) * y[i] * y[j]
+						}
+						b[i] += confidence * y[i]
+					}
+				}
+
+				x, err := util.CholeskySolve(a, b, factors)
+				if err != nil {
+					errs[w] = err
+					return
+				}
+				solutions[r] = x
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, k := range rows {
+		target[k] = solutions[i]
+	}
+
+	return nil
+}
+
+// DoInsertTop inserts v into the descending list, keeping at most
+// util.MaxAlsProjectRecommendCount entries.
+func (this *AlsProjectRecommendTask) DoInsertTop(list []model.ProjectRecommend, v model.ProjectRecommend) []model.ProjectRecommend {
+	if len(list) >= util.MaxAlsProjectRecommendCount && list[len(list)-1].Score >= v.Score {
+		return list
+	}
+
+	i := len(list)
+	for i > 0 && list[i-1].Score < v.Score {
+		i--
+	}
+
+	list = append(list, model.ProjectRecommend{})
+	copy(list[i+1:], list[i:])
+	list[i] = v
+
+	if len(list) > util.MaxAlsProjectRecommendCount {
+		list = list[:util.MaxAlsProjectRecommendCount]
+	}
+
+	return list
+}
+
+// DoSaveFactors writes the learned factors as lines of
+// "user|project \t id \t f1,f2,...".
+func (this *AlsProjectRecommendTask) DoSaveFactors(factorFile string) error {
+	output, err := os.OpenFile(factorFile, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	write := func(kind string, counts map[int64]map[int64]float64, factorMap map[int64][]float64) error {
+		for _, k := range this.DoSortedIds(counts) {
+			v := factorMap[k]
+			values := make([]string, len(v))
+			for i, vv := range v {
+				values[i] = strconv.FormatFloat(vv, 'g', -1, 64)
+			}
+
+			_, err := output.WriteString(kind + "\t" + strconv.FormatInt(k, 10) + "\t" + strings.Join(values, ",") + "\n")
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err = write("user", this.UserProjectCountMap, this.UserFactorMap)
+	if err != nil {
+		return err
+	}
+
+	return write("project", this.ProjectUserCountMap, this.ProjectFactorMap)
+}
+
+func (this *AlsProjectRecommendTask) DoDataTask(inputFiles []string, outputFile string, arg interface{}) error {
+	// 检查输入参数信息
+	if len(inputFiles) < 4 {
+		return errors.New("DoDataTask AlsProjectRecommendTask check fail, inputFiles len is not correct")
+	}
+
+	// 设置文件名称
+	projectFile := inputFiles[0]
+	ideaFile := inputFiles[1]
+	commentFile := inputFiles[2]
+	userProjectRelationFile := inputFiles[3]
+
+	var err error
+	// 处理项目创意信息
+	err = this.DoProcessIdeaFile(ideaFile)
+	if err != nil {
+		return err
+	}
+
+	// 处理项目评论信息
+	err = this.DoProcessCommentFile(commentFile)
+	if err != nil {
+		return err
+	}
+
+	// 处理项目用户关系信息
+	err = this.DoProcessUserProjectRelationFile(userProjectRelationFile)
+	if err != nil {
+		return err
+	}
+
+	// 读取输入文件
+	input, err := OpenInput("projects", projectFile, &model.ProjectRecord{})
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	// 创建生成结果文件
+	output, err := os.OpenFile(outputFile, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	runtime.GOMAXPROCS(runtime.NumCPU())
+
+	jobs := make(chan Job, this.Workers)
+	done := make(chan struct{}, this.Workers)
+	result := make(chan Result, this.Workers)
+
+	// 将需要并发处理的任务添加到jobs的channel中
+	go this.AddJobs(jobs, result, input)
+
+	// 根据cpu的数量启动对应个数的goroutines从jobs争夺任务进行处理
+	for i := 0; i < this.Workers; i++ {
+		go this.DoJobs(done, jobs)
+	}
+
+	// 等待所有worker routiines的完成结果, 并将结果通知主routine
+	go this.AwaitJobDone(done, result)
+
+	return this.DoResult(result, output)
+}
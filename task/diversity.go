@@ -0,0 +1,136 @@
+package task
+
+import (
+	"fmt"
+
+	"doraemon/model"
+	"doraemon/util"
+)
+
+const (
+	DiversityModeNone = "none" // 不重排
+	DiversityModeMMR  = "mmr"  // 最大边际相关性
+	DiversityModeCap  = "cap"  // 限制相似条目数量
+)
+
+// ProjectSimilarity measures how alike two projects are, from 0 to 1.
+type ProjectSimilarity func(task *ProjectRecommendTask, a *model.Project, b *model.Project) float64
+
+// ProjectSimilarities are the similarity functions the diversity stage of
+// ProjectRecommendTask can be configured with, by name.
+var ProjectSimilarities = map[string]ProjectSimilarity{
+	"owner":       ProjectOwnerSimilarity,
+	"title":       ProjectTitleSimilarity,
+	"owner_title": ProjectOwnerTitleSimilarity,
+}
+
+// UserSimilarity measures how alike two users are, from 0 to 1.
+type UserSimilarity func(task *UserRecommendTask, a *model.User, b *model.User) float64
+
+// UserSimilarities are the similarity functions the diversity stage of
+// UserRecommendTask can be configured with, by name.
+var UserSimilarities = map[string]UserSimilarity{
+	"followers":   UserFollowerSimilarity,
+	"description": UserDescriptionSimilarity,
+}
+
+// ProjectOwnerSimilarity is 1 for projects of the same owner and 0 otherwise.
+func ProjectOwnerSimilarity(task *ProjectRecommendTask, a *model.Project, b *model.Project) float64 {
+	if a.UserId == b.UserId {
+		return 1
+	}
+	return 0
+}
+
+// ProjectTitleSimilarity is the Jaccard similarity of the title terms.
+func ProjectTitleSimilarity(task *ProjectRecommendTask, a *model.Project, b *model.Project) float64 {
+	return util.Jaccard(termSet(a.Title), termSet(b.Title))
+}
+
+// ProjectOwnerTitleSimilarity is the larger of the owner and the title
+// similarity.
+func ProjectOwnerTitleSimilarity(task *ProjectRecommendTask, a *model.Project, b *model.Project) float64 {
+	if a.UserId == b.UserId {
+		return 1
+	}
+	return ProjectTitleSimilarity(task, a, b)
+}
+
+// UserFollowerSimilarity is the Jaccard similarity of the follower sets.
+func UserFollowerSimilarity(task *UserRecommendTask, a *model.User, b *model.User) float64 {
+	followersA := task.UserRalationMap[a.Id]
+	followersB := task.UserRalationMap[b.Id]
+	if len(followersA) > len(followersB) {
+		followersA, followersB = followersB, followersA
+	}
+
+	if len(followersB) == 0 {
+		return 0
+	}
+
+	var common int
+	for k, _ := range followersA {
+		if _, ok := followersB[k]; ok {
+			common += 1
+		}
+	}
+
+	return float64(common) / float64(len(followersA)+len(followersB)-common)
+}
+
+// UserDescriptionSimilarity is the Jaccard similarity of the description
+// terms.
+func UserDescriptionSimilarity(task *UserRecommendTask, a *model.User, b *model.User) float64 {
+	return util.Jaccard(termSet(a.Description), termSet(b.Description))
+}
+
+func termSet(text string) map[string]bool {
+	terms := make(map[string]bool)
+	for _, v := range util.Tokenize(text) {
+		terms[v] = true
+	}
+	return terms
+}
+
+// CheckDiversityConf reports whether conf names a known mode with usable
+// parameters. known tells whether conf.Similarity is registered for the
+// task.
+func CheckDiversityConf(conf model.DiversityConf, known bool) error {
+	switch conf.Mode {
+	case DiversityModeNone:
+		return nil
+	case DiversityModeMMR:
+		if conf.Lambda < 0 || conf.Lambda > 1 {
+			return fmt.Errorf("CheckDiversityConf check fail, Lambda %v must be between 0 and 1", conf.Lambda)
+		}
+	case DiversityModeCap:
+		if conf.MaxSimilar < 1 {
+			return fmt.Errorf("CheckDiversityConf check fail, MaxSimilar %v must be positive", conf.MaxSimilar)
+		}
+	default:
+		return fmt.Errorf("CheckDiversityConf check fail, unknown Mode %q", conf.Mode)
+	}
+
+	if !known {
+		return fmt.Errorf("CheckDiversityConf check fail, unknown Similarity %q", conf.Similarity)
+	}
+
+	return nil
+}
+
+// Diversify returns the new order of the first CandidateCount entries of a
+// list sorted by relevance.
+func Diversify(conf model.DiversityConf, relevance []float64, similarity func(i, j int) float64) []int {
+	switch conf.Mode {
+	case DiversityModeMMR:
+		return util.MMR(relevance, conf.Lambda, similarity)
+	case DiversityModeCap:
+		return util.CapSimilar(len(relevance), conf.Threshold, conf.MaxSimilar, similarity)
+	}
+
+	order := make([]int, len(relevance))
+	for i, _ := range order {
+		order[i] = i
+	}
+	return order
+}
@@ -0,0 +1,88 @@
+package task
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"doraemon/model"
+	"doraemon/modual/reader"
+)
+
+// rejectFile is the file the rejected lines of all inputs are written to,
+// opened by the first OpenInput.
+var rejectFile *os.File
+
+// OpenInput opens inputFile for reading records like record, with the schema
+// configured for the input name and InputTime as its default time format.
+// Rejected lines are written to the configured reject file.
+func OpenInput(name string, inputFile string, record interface{}) (*reader.Reader, error) {
+	err := CheckRejectConf(model.GlobalConf.Rejects)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, ok := model.GlobalConf.Inputs[name]
+	if !ok {
+		return nil, fmt.Errorf("OpenInput check fail, no schema for input %q", name)
+	}
+
+	// 列定义中未配置的时间格式和时区使用InputTime
+	if len(schema.Time.Layouts) == 0 {
+		schema.Time.Layouts = model.GlobalConf.InputTime.Layouts
+	}
+	if schema.Time.Location == "" {
+		schema.Time.Location = model.GlobalConf.InputTime.Location
+	}
+
+	input, err := reader.Open(inputFile, schema, record)
+	if err != nil {
+		return nil, fmt.Errorf("OpenInput %s %s fail, %v", name, inputFile, err)
+	}
+
+	if model.GlobalConf.Rejects.File != "" {
+		if rejectFile == nil {
+			rejectFile, err = os.OpenFile(model.GlobalConf.Rejects.File, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+			if err != nil {
+				input.Close()
+				return nil, err
+			}
+		}
+		input.LogRejects(rejectFile)
+	}
+
+	return input, nil
+}
+
+// CheckRejectConf reports whether conf has a usable MaxRatio.
+func CheckRejectConf(conf model.RejectConf) error {
+	if conf.MaxRatio < 0 || conf.MaxRatio > 1 {
+		return fmt.Errorf("CheckRejectConf check fail, MaxRatio %v must be between 0 and 1", conf.MaxRatio)
+	}
+
+	return nil
+}
+
+// CheckInput prints the records read and rejected from the input name, which
+// has been read to the end, and fails when the share of rejected lines is
+// above Rejects.MaxRatio.
+func CheckInput(name string, input *reader.Reader) error {
+	stats := input.Stats()
+	fmt.Printf("input %s\trecords %d\trejects %d\n", name, stats.Records, stats.Rejected())
+
+	var reasons []string
+	for k, _ := range stats.Rejects {
+		reasons = append(reasons, k)
+	}
+	sort.Strings(reasons)
+
+	for _, v := range reasons {
+		fmt.Printf("input %s\treject %s\t%d\n", name, v, stats.Rejects[v])
+	}
+
+	if ratio := stats.RejectRatio(); ratio > model.GlobalConf.Rejects.MaxRatio {
+		return fmt.Errorf("CheckInput %s fail, %d of %d lines rejected, ratio %.4f above MaxRatio %v", name, stats.Rejected(), stats.Records+stats.Rejected(), ratio, model.GlobalConf.Rejects.MaxRatio)
+	}
+
+	return nil
+}
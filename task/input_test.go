@@ -0,0 +1,131 @@
+package task
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"doraemon/model"
+)
+
+// writeInput writes good valid project lines followed by bad lines with a
+// non numeric id into a file in dir.
+func writeInput(t *testing.T, dir string, good int, bad int) string {
+	var lines []string
+	for i := 1; i <= good; i++ {
+		lines = append(lines, fmt.Sprintf("%d\tproject %d\t10\t2013-01-01 10:00:00", i, i))
+	}
+	for i := 1; i <= bad; i++ {
+		lines = append(lines, fmt.Sprintf("x%d\tproject\t10\t2013-01-01 10:00:00", i))
+	}
+
+	file := filepath.Join(dir, fmt.Sprintf("projects_%d_%d.tsv", good, bad))
+	err := ioutil.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+// readInput reads the projects in file to the end and checks them.
+func readInput(file string) error {
+	input, err := OpenInput("projects", file, &model.ProjectRecord{})
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	for {
+		var record model.ProjectRecord
+		err = input.Read(&record)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return CheckInput("projects", input)
+}
+
+func TestCheckInput(t *testing.T) {
+	rejects := model.GlobalConf.Rejects
+	defer func() { model.GlobalConf.Rejects = rejects }()
+
+	dir, err := ioutil.TempDir("", "input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name     string
+		good     int
+		bad      int
+		maxRatio float64
+		fail     bool
+	}{
+		{"no rejects", 10, 0, 0, false},
+		{"below", 99, 1, 0.02, false},
+		{"equal", 99, 1, 0.01, false},
+		{"above", 99, 1, 0.005, true},
+		{"above zero", 99, 1, 0, true},
+		{"all rejected", 0, 3, 0.5, true},
+		{"all rejected allowed", 0, 3, 1, false},
+	}
+
+	for _, v := range tests {
+		model.GlobalConf.Rejects = model.RejectConf{MaxRatio: v.maxRatio}
+
+		err = readInput(writeInput(t, dir, v.good, v.bad))
+		if (err != nil) != v.fail {
+			t.Errorf("%s: got %v, want fail %v", v.name, err, v.fail)
+		}
+	}
+}
+
+func TestOpenInputRejectFile(t *testing.T) {
+	rejects := model.GlobalConf.Rejects
+	defer func() { model.GlobalConf.Rejects = rejects }()
+
+	dir, err := ioutil.TempDir("", "input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 跳过的行写入Rejects.File, 每个输入共用同一个文件
+	model.GlobalConf.Rejects = model.RejectConf{File: filepath.Join(dir, "rejects.tsv"), MaxRatio: 1}
+	defer func() {
+		rejectFile.Close()
+		rejectFile = nil
+	}()
+
+	file := filepath.Join(dir, "projects.tsv")
+	err = ioutil.WriteFile(file, []byte("1\tAlpha\t10\t2013-01-01 10:00:00\n2\tBeta \"b\"\t11\n"+
+		"x\tGamma\t12\t2013-01-01 10:00:00\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = readInput(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(model.GlobalConf.Rejects.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := file + "\t2\tfield count\t\"2\\tBeta \\\"b\\\"\\t11\"\n" +
+		file + "\t3\tinvalid id\t\"x\\tGamma\\t12\\t2013-01-01 10:00:00\"\n"
+	if string(data) != want {
+		t.Errorf("got reject lines %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,368 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+
+	"doraemon/model"
+	"doraemon/util"
+)
+
+// Publisher writes the results of a DataTask to an external store, next to
+// the output file.
+type Publisher interface {
+	Publish(name string, top interface{}, items map[int64]interface{}) error
+	Rollback(name string, version string) (string, error)
+}
+
+// NewPublisher returns the Publisher enabled in model.GlobalConf, or nil if
+// results are only written to the output file.
+func NewPublisher() Publisher {
+	if !model.GlobalConf.MemcachedPublish || model.GlobalConf.MemcachedHost == "" {
+		return nil
+	}
+
+	return NewMemcachedPublisher(model.GlobalConf.MemcachedHost)
+}
+
+// PublishResult publishes the ranked list and the per-entity records of the
+// named task with the configured Publisher, if any.
+func PublishResult(name string, top interface{}, items map[int64]interface{}) error {
+	publisher := NewPublisher()
+	if publisher == nil {
+		return nil
+	}
+
+	return publisher.Publish(name, top, items)
+}
+
+// RollbackResult makes the named task serve an earlier published version:
+// the given one, or the one published before the current version if
+// version is empty. It returns the version now being served.
+func RollbackResult(name string, version string) (string, error) {
+	publisher := NewPublisher()
+	if publisher == nil {
+		return "", errors.New("RollbackResult check fail, no publisher is configured")
+	}
+
+	return publisher.Rollback(name, version)
+}
+
+// MemcachedPublisher stores recommendation results in memcached, so that the
+// web tier can read them without copying the output file around.
+//
+// Every Publish writes a new version, named by the unix time of the run in
+// nanoseconds, and keys are laid out as:
+//
+//	<prefix>:<name>:current              version readers should use
+//	<prefix>:<name>:versions             comma separated kept versions, oldest first
+//	<prefix>:<name>:<version>:top        JSON array of the ranked list, if any
+//	<prefix>:<name>:<version>:item:<id>  JSON record of a single entity
+//	<prefix>:<name>:<version>:ids        comma separated ids of the item keys
+//
+// <prefix> is MemcachedKeyPrefix, or AppName if it is empty, and <name> is
+// the adapter name of the task. Readers get the current key first and then
+// the keys of that version. The current key is only flipped after all keys
+// of a version are written, so readers never see a half-written result.
+// Versions older than the last MemcachedKeepVersions ones are deleted.
+//
+// A value larger than util.MemcachedItemSize is stored in chunks: the key
+// itself holds "chunks:<n>" and the data is split over <key>:0 ...
+// <key>:<n-1>, which readers concatenate in order.
+type MemcachedPublisher struct {
+	Client       *memcache.Client
+	Prefix       string
+	Expiration   int32
+	KeepVersions int
+}
+
+func NewMemcachedPublisher(host string) *MemcachedPublisher {
+	servers := strings.Split(host, ",")
+	for i, v := range servers {
+		servers[i] = strings.TrimSpace(v)
+	}
+
+	client := memcache.New(servers...)
+	client.Timeout = time.Duration(util.MemcachedTimeout) * time.Millisecond
+
+	prefix := model.GlobalConf.MemcachedKeyPrefix
+	if prefix == "" {
+		prefix = model.GlobalConf.AppName
+	}
+
+	keepVersions := model.GlobalConf.MemcachedKeepVersions
+	if keepVersions < 1 {
+		keepVersions = 1
+	}
+
+	return &MemcachedPublisher{
+		Client:       client,
+		Prefix:       prefix,
+		Expiration:   model.GlobalConf.MemcachedExpiration,
+		KeepVersions: keepVersions,
+	}
+}
+
+func (this *MemcachedPublisher) String() string {
+	if this == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("[MemcachedPublisher](%+v)", *this)
+}
+
+// Key joins parts into a key under the publisher prefix.
+func (this *MemcachedPublisher) Key(parts ...string) string {
+	return this.Prefix + ":" + strings.Join(parts, ":")
+}
+
+func (this *MemcachedPublisher) Publish(name string, top interface{}, items map[int64]interface{}) error {
+	versions, err := this.Versions(name)
+	if err != nil {
+		return err
+	}
+
+	// 版本已存在时不能覆盖, 以免改写正在使用的数据
+	version := strconv.FormatInt(time.Now().UnixNano(), 10)
+	for _, v := range versions {
+		if v == version {
+			return fmt.Errorf("Publish %s check fail, version %q is already published", name, version)
+		}
+	}
+
+	// 个性化推荐任务没有全局排行
+	if top != nil {
+		data, err := json.Marshal(top)
+		if err != nil {
+			return err
+		}
+
+		err = this.Set(this.Key(name, version, "top"), data)
+		if err != nil {
+			return err
+		}
+	}
+
+	ids := make([]string, 0, len(items))
+	for k, v := range items {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		id := strconv.FormatInt(k, 10)
+		err = this.Set(this.Key(name, version, "item", id), data)
+		if err != nil {
+			return err
+		}
+
+		ids = append(ids, id)
+	}
+
+	err = this.Set(this.Key(name, version, "ids"), []byte(strings.Join(ids, ",")))
+	if err != nil {
+		return err
+	}
+
+	versions = append(versions, version)
+	var expired []string
+	if len(versions) > this.KeepVersions {
+		expired = versions[:len(versions)-this.KeepVersions]
+		versions = versions[len(versions)-this.KeepVersions:]
+	}
+
+	err = this.Set(this.Key(name, "versions"), []byte(strings.Join(versions, ",")))
+	if err != nil {
+		return err
+	}
+
+	// 所有数据写入完成后再切换当前版本
+	err = this.Set(this.Key(name, "current"), []byte(version))
+	if err != nil {
+		return err
+	}
+
+	for _, v := range expired {
+		err = this.DeleteVersion(name, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (this *MemcachedPublisher) Rollback(name string, version string) (string, error) {
+	versions, err := this.Versions(name)
+	if err != nil {
+		return "", err
+	}
+
+	current, err := this.Get(this.Key(name, "current"))
+	if err != nil && err != memcache.ErrCacheMiss {
+		return "", err
+	}
+
+	index := -1
+	for i, v := range versions {
+		if version == "" && v == string(current) {
+			index = i - 1
+		} else if version != "" && v == version {
+			index = i
+		}
+	}
+
+	if index < 0 {
+		if version == "" {
+			return "", fmt.Errorf("Rollback %s check fail, no version before %q is kept", name, current)
+		}
+		return "", fmt.Errorf("Rollback %s check fail, version %q is not kept", name, version)
+	}
+
+	err = this.Set(this.Key(name, "current"), []byte(versions[index]))
+	if err != nil {
+		return "", err
+	}
+
+	return versions[index], nil
+}
+
+// Versions returns the kept versions of the named task, oldest first.
+func (this *MemcachedPublisher) Versions(name string) ([]string, error) {
+	data, err := this.Get(this.Key(name, "versions"))
+	if err == memcache.ErrCacheMiss {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, v := range strings.Split(string(data), ",") {
+		if v != "" {
+			versions = append(versions, v)
+		}
+	}
+
+	return versions, nil
+}
+
+// DeleteVersion removes all keys written for a version of the named task.
+func (this *MemcachedPublisher) DeleteVersion(name string, version string) error {
+	data, err := this.Get(this.Key(name, version, "ids"))
+	if err != nil && err != memcache.ErrCacheMiss {
+		return err
+	}
+
+	var keys []string
+	for _, v := range strings.Split(string(data), ",") {
+		if v != "" {
+			keys = append(keys, this.Key(name, version, "item", v))
+		}
+	}
+	keys = append(keys, this.Key(name, version, "top"), this.Key(name, version, "ids"))
+
+	for _, v := range keys {
+		err = this.Delete(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Set stores value under key, splitting it into chunks if it does not fit
+// into a single memcached item. The chunks are written before the key
+// itself, so a reader never sees a chunk count whose chunks are missing.
+func (this *MemcachedPublisher) Set(key string, value []byte) error {
+	size := util.MemcachedItemSize
+	if len(value) <= size {
+		return this.Client.Set(&memcache.Item{Key: key, Value: value, Expiration: this.Expiration})
+	}
+
+	var number int = 0
+	for start := 0; start < len(value); start += size {
+		end := start + size
+		if end > len(value) {
+			end = len(value)
+		}
+
+		chunkKey := key + ":" + strconv.Itoa(number)
+		err := this.Client.Set(&memcache.Item{Key: chunkKey, Value: value[start:end], Expiration: this.Expiration})
+		if err != nil {
+			return err
+		}
+
+		number += 1
+	}
+
+	header := []byte("chunks:" + strconv.Itoa(number))
+	return this.Client.Set(&memcache.Item{Key: key, Value: header, Expiration: this.Expiration})
+}
+
+// Get returns the value stored under key by Set, joining its chunks.
+func (this *MemcachedPublisher) Get(key string) ([]byte, error) {
+	item, err := this.Client.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	number, ok := this.chunkCount(item.Value)
+	if !ok {
+		return item.Value, nil
+	}
+
+	var value []byte
+	for i := 0; i < number; i++ {
+		chunk, err := this.Client.Get(key + ":" + strconv.Itoa(i))
+		if err != nil {
+			return nil, err
+		}
+		value = append(value, chunk.Value...)
+	}
+
+	return value, nil
+}
+
+// Delete removes key and its chunks, if any. Missing keys are not an error.
+func (this *MemcachedPublisher) Delete(key string) error {
+	item, err := this.Client.Get(key)
+	if err == memcache.ErrCacheMiss {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	number, _ := this.chunkCount(item.Value)
+	for i := 0; i < number; i++ {
+		err = this.Client.Delete(key + ":" + strconv.Itoa(i))
+		if err != nil && err != memcache.ErrCacheMiss {
+			return err
+		}
+	}
+
+	err = this.Client.Delete(key)
+	if err != nil && err != memcache.ErrCacheMiss {
+		return err
+	}
+
+	return nil
+}
+
+func (this *MemcachedPublisher) chunkCount(value []byte) (int, bool) {
+	if !strings.HasPrefix(string(value), "chunks:") {
+		return 0, false
+	}
+
+	number, err := strconv.Atoi(string(value[len("chunks:"):]))
+	if err != nil {
+		return 0, false
+	}
+
+	return number, true
+}
@@ -0,0 +1,293 @@
+package task
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bradfitz/gomemcache/memcache"
+
+	"doraemon/util"
+)
+
+// fakeMemcached is a memcached stand-in speaking the get, gets, set and
+// delete commands of the text protocol.
+type fakeMemcached struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	items    map[string][]byte
+}
+
+func newFakeMemcached(t *testing.T) *fakeMemcached {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	this := &fakeMemcached{listener: listener, items: make(map[string][]byte)}
+	go this.serve()
+
+	return this
+}
+
+func (this *fakeMemcached) serve() {
+	for {
+		conn, err := this.listener.Accept()
+		if err != nil {
+			return
+		}
+		go this.handle(conn)
+	}
+}
+
+func (this *fakeMemcached) handle(conn net.Conn) {
+	defer conn.Close()
+
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+
+		this.mutex.Lock()
+		switch args[0] {
+		case "get", "gets":
+			for _, key := range args[1:] {
+				if value, ok := this.items[key]; ok {
+					fmt.Fprintf(rw, "VALUE %s 0 %d 1\r\n%s\r\n", key, len(value), value)
+				}
+			}
+			fmt.Fprint(rw, "END\r\n")
+		case "set":
+			size, _ := strconv.Atoi(args[4])
+			value := make([]byte, size+2)
+			_, err = io.ReadFull(rw, value)
+			if err == nil {
+				this.items[args[1]] = value[:size]
+				fmt.Fprint(rw, "STORED\r\n")
+			}
+		case "delete":
+			if _, ok := this.items[args[1]]; ok {
+				delete(this.items, args[1])
+				fmt.Fprint(rw, "DELETED\r\n")
+			} else {
+				fmt.Fprint(rw, "NOT_FOUND\r\n")
+			}
+		default:
+			fmt.Fprint(rw, "ERROR\r\n")
+		}
+		this.mutex.Unlock()
+
+		if err != nil || rw.Flush() != nil {
+			return
+		}
+	}
+}
+
+func (this *fakeMemcached) Close() {
+	this.listener.Close()
+}
+
+// Item returns the raw value stored under key.
+func (this *fakeMemcached) Item(key string) ([]byte, bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	value, ok := this.items[key]
+	return value, ok
+}
+
+// Keys returns the number of stored keys.
+func (this *fakeMemcached) Keys() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	return len(this.items)
+}
+
+func newTestPublisher(server *fakeMemcached) *MemcachedPublisher {
+	return &MemcachedPublisher{
+		Client:       memcache.New(server.listener.Addr().String()),
+		Prefix:       "test",
+		KeepVersions: 2,
+	}
+}
+
+func TestMemcachedPublisherChunks(t *testing.T) {
+	itemSize := util.MemcachedItemSize
+	util.MemcachedItemSize = 10
+	defer func() { util.MemcachedItemSize = itemSize }()
+
+	server := newFakeMemcached(t)
+	defer server.Close()
+	publisher := newTestPublisher(server)
+
+	tests := []struct {
+		name   string
+		value  string
+		chunks []string
+	}{
+		{"empty", "", nil},
+		{"single item", "0123456789", nil},
+		{"two chunks", "0123456789a", []string{"0123456789", "a"}},
+		{"three chunks", "0123456789abcdefghijABCDE", []string{"0123456789", "abcdefghij", "ABCDE"}},
+	}
+
+	for i, v := range tests {
+		key := publisher.Key("value", strconv.Itoa(i))
+		err := publisher.Set(key, []byte(v.value))
+		if err != nil {
+			t.Errorf("%s: %v", v.name, err)
+			continue
+		}
+
+		header, _ := server.Item(key)
+		if v.chunks == nil {
+			if string(header) != v.value {
+				t.Errorf("%s: stored %q, want %q", v.name, header, v.value)
+			}
+		} else {
+			if want := "chunks:" + strconv.Itoa(len(v.chunks)); string(header) != want {
+				t.Errorf("%s: stored %q, want %q", v.name, header, want)
+			}
+
+			for j, chunk := range v.chunks {
+				if value, _ := server.Item(key + ":" + strconv.Itoa(j)); string(value) != chunk {
+					t.Errorf("%s: chunk %d is %q, want %q", v.name, j, value, chunk)
+				}
+			}
+		}
+
+		value, err := publisher.Get(key)
+		if err != nil || string(value) != v.value {
+			t.Errorf("%s: Get = %q, %v, want %q", v.name, value, err, v.value)
+		}
+
+		err = publisher.Delete(key)
+		if err != nil {
+			t.Errorf("%s: %v", v.name, err)
+		}
+	}
+
+	if n := server.Keys(); n != 0 {
+		t.Errorf("%d keys are left after Delete", n)
+	}
+
+	err := publisher.Delete(publisher.Key("missing"))
+	if err != nil {
+		t.Errorf("Delete of a missing key: %v", err)
+	}
+}
+
+func TestMemcachedPublisherVersions(t *testing.T) {
+	itemSize := util.MemcachedItemSize
+	util.MemcachedItemSize = 16
+	defer func() { util.MemcachedItemSize = itemSize }()
+
+	server := newFakeMemcached(t)
+	defer server.Close()
+	publisher := newTestPublisher(server)
+
+	var published []string
+	for i := 0; i < 3; i++ {
+		top := []int64{int64(i), 2, 3, 4, 5, 6, 7, 8}
+		items := map[int64]interface{}{int64(i): "item", 100: i}
+
+		err := publisher.Publish("top", top, items)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		current, err := publisher.Get(publisher.Key("top", "current"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		published = append(published, string(current))
+
+		data, err := publisher.Get(publisher.Key("top", string(current), "top"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []int64
+		if err = json.Unmarshal(data, &got); err != nil || !reflect.DeepEqual(got, top) {
+			t.Errorf("publish %d: top is %s, %v, want %v", i, data, err, top)
+		}
+
+		data, err = publisher.Get(publisher.Key("top", string(current), "item", "100"))
+		if err != nil || string(data) != strconv.Itoa(i) {
+			t.Errorf("publish %d: item 100 is %q, %v, want %d", i, data, err, i)
+		}
+	}
+
+	// 只保留最近的2个版本, 更早的版本被删除
+	versions, err := publisher.Versions("top")
+	if err != nil || !reflect.DeepEqual(versions, published[1:]) {
+		t.Errorf("Versions = %v, %v, want %v", versions, err, published[1:])
+	}
+
+	for _, v := range []string{"top", "ids", "item:0", "item:100"} {
+		if _, err := publisher.Get(publisher.Key("top", published[0], v)); err != memcache.ErrCacheMiss {
+			t.Errorf("%s of expired version is not deleted, %v", v, err)
+		}
+	}
+
+	version, err := publisher.Rollback("top", "")
+	if err != nil || version != published[1] {
+		t.Errorf("Rollback = %q, %v, want %q", version, err, published[1])
+	}
+
+	_, err = publisher.Rollback("top", "")
+	if err == nil {
+		t.Errorf("Rollback before the oldest kept version got no error")
+	}
+
+	_, err = publisher.Rollback("top", published[0])
+	if err == nil {
+		t.Errorf("Rollback to an expired version got no error")
+	}
+
+	version, err = publisher.Rollback("top", published[2])
+	if err != nil || version != published[2] {
+		t.Errorf("Rollback(%q) = %q, %v", published[2], version, err)
+	}
+
+	current, _ := publisher.Get(publisher.Key("top", "current"))
+	if string(current) != published[2] {
+		t.Errorf("current is %q, want %q", current, published[2])
+	}
+}
+
+func TestMemcachedPublisherNoTop(t *testing.T) {
+	server := newFakeMemcached(t)
+	defer server.Close()
+	publisher := newTestPublisher(server)
+
+	err := publisher.Publish("personal", nil, map[int64]interface{}{1: []int64{2, 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current, _ := publisher.Get(publisher.Key("personal", "current"))
+	if _, err = publisher.Get(publisher.Key("personal", string(current), "top")); err != memcache.ErrCacheMiss {
+		t.Errorf("top of a task without ranked list is %v, want a miss", err)
+	}
+
+	ids, _ := publisher.Get(publisher.Key("personal", string(current), "ids"))
+	if string(ids) != "1" {
+		t.Errorf("ids are %q, want %q", ids, "1")
+	}
+}
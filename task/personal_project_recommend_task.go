@@ -0,0 +1,249 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"time"
+
+	"doraemon/model"
+	"doraemon/util"
+)
+
+func init() {
+	Register("PersonalProjectRecommend", NewPersonalProjectRecommendTask())
+}
+
+// PersonalProjectRecommendTask recommends projects to every user with
+// item-based collaborative filtering. Two projects are similar when the same
+// users follow, join, or create ideas and comments in both of them.
+type PersonalProjectRecommendTask struct {
+	*ProjectRecommendTask
+	UserProjectMap map[int64]map[int64]bool // 用户参与过的项目
+}
+
+func NewPersonalProjectRecommendTask() *PersonalProjectRecommendTask {
+	return &PersonalProjectRecommendTask{
+		ProjectRecommendTask: NewProjectRecommendTask(),
+		UserProjectMap:       make(map[int64]map[int64]bool),
+	}
+}
+
+func (this *PersonalProjectRecommendTask) DoResult(result <-chan Result, output *os.File) error {
+	var number int = 0
+	for _ = range result {
+		number += 1
+
+		if number%10000 == 0 {
+			fmt.Printf("%d\t%s\n", number, time.Now().String())
+		}
+	}
+
+	// 输入文件读取失败或跳过的行过多时不生成结果
+	if this.InputErr != nil {
+		return this.InputErr
+	}
+
+	// 汇总用户关注/加入、创意、评论过的项目
+	this.DoCollectUserProjects(this.ProjectUserMap)
+	this.DoCollectUserProjects(this.ProjectIdeaMap)
+	this.DoCollectUserProjects(this.ProjectCommentMap)
+
+	// 计算项目之间的相似度
+	neighbors := this.DoCalculateSimilarity()
+
+	items := make(map[int64]interface{})
+	for userId, projects := range this.UserProjectMap {
+		scores := make(map[int64]float64)
+		for projectId, _ := range projects {
+			for _, v := range neighbors[projectId] {
+				// 过滤用户已经参与过的项目, 包括已取消关注或已退出的项目
+				if projects[v.Id] || this.ProjectFormerUserMap[v.Id][userId] {
+					continue
+				}
+
+				scores[v.Id] += v.Score
+			}
+		}
+
+		if len(scores) == 0 {
+			continue
+		}
+
+		var projectRecommends []model.ProjectRecommend
+		for k, v := range scores {
+			var projectRecommend model.ProjectRecommend
+			projectRecommend.Id = k
+			projectRecommend.Title = this.ProjectInfoMap[k].Title
+			projectRecommend.UserId = this.ProjectInfoMap[k].UserId
+			projectRecommend.Score = v
+
+			projectRecommends = append(projectRecommends, projectRecommend)
+		}
+
+		util.DescByField(projectRecommends, "Score")
+		if int64(len(projectRecommends)) > util.MaxPersonalProjectRecommendCount {
+			projectRecommends = projectRecommends[:util.MaxPersonalProjectRecommendCount]
+		}
+
+		var personalProjectRecommend model.PersonalProjectRecommend
+		personalProjectRecommend.UserId = userId
+		personalProjectRecommend.Projects = projectRecommends
+
+		data, err := json.Marshal(&personalProjectRecommend)
+		if err != nil {
+			continue
+		}
+
+		data = append(data, '\n')
+		_, _ = output.WriteString(string(data))
+
+		items[userId] = personalProjectRecommend
+	}
+
+	// 将推荐结果写入memcached
+	return PublishResult("PersonalProjectRecommend", nil, items)
+}
+
+func (this *PersonalProjectRecommendTask) DoCollectUserProjects(data map[int64]map[int64][]int64) {
+	for projectId, users := range data {
+		// 只推荐项目文件中存在的项目
+		if _, ok := this.ProjectInfoMap[projectId]; !ok {
+			continue
+		}
+
+		for userId, _ := range users {
+			if v, ok := this.UserProjectMap[userId]; ok {
+				v[projectId] = true
+			} else {
+				subMap := make(map[int64]bool)
+				subMap[projectId] = true
+				this.UserProjectMap[userId] = subMap
+			}
+		}
+	}
+}
+
+// DoCalculateSimilarity returns the most similar projects of every project,
+// using the cosine similarity of their user sets:
+//
+//	sim(i, j) = |users(i) ∩ users(j)| / sqrt(|users(i)| * |users(j)|)
+//
+// Users who took part in more than util.PersonalProjectMaxUserProjects
+// projects are left out of the co-occurrence counts.
+func (this *PersonalProjectRecommendTask) DoCalculateSimilarity() map[int64][]model.ProjectRecommend {
+	projectCount := make(map[int64]int64)
+	cooccurrence := make(map[int64]map[int64]int64)
+	for _, projects := range this.UserProjectMap {
+		for projectId, _ := range projects {
+			projectCount[projectId] += 1
+		}
+
+		if len(projects) > util.PersonalProjectMaxUserProjects {
+			continue
+		}
+
+		for i, _ := range projects {
+			subMap, ok := cooccurrence[i]
+			if !ok {
+				subMap = make(map[int64]int64)
+				cooccurrence[i] = subMap
+			}
+
+			for j, _ := range projects {
+				if i != j {
+					subMap[j] += 1
+				}
+			}
+		}
+	}
+
+	neighbors := make(map[int64][]model.ProjectRecommend)
+	for i, v := range cooccurrence {
+		var similarities []model.ProjectRecommend
+		for j, count := range v {
+			var similarity model.ProjectRecommend
+			similarity.Id = j
+			similarity.Score = float64(count) / math.Sqrt(float64(projectCount[i]*projectCount[j]))
+
+			similarities = append(similarities, similarity)
+		}
+
+		util.DescByField(similarities, "Score")
+		if len(similarities) > util.PersonalProjectNeighborCount {
+			similarities = similarities[:util.PersonalProjectNeighborCount]
+		}
+
+		neighbors[i] = similarities
+	}
+
+	return neighbors
+}
+
+func (this *PersonalProjectRecommendTask) DoDataTask(inputFiles []string, outputFile string, arg interface{}) error {
+	// 检查输入参数信息
+	if len(inputFiles) < 4 {
+		return errors.New("DoDataTask PersonalProjectRecommendTask check fail, inputFiles len is not correct")
+	}
+
+	// 设置文件名称
+	projectFile := inputFiles[0]
+	ideaFile := inputFiles[1]
+	commentFile := inputFiles[2]
+	userProjectRelationFile := inputFiles[3]
+
+	var err error
+	// 处理项目创意信息
+	err = this.DoProcessIdeaFile(ideaFile)
+	if err != nil {
+		return err
+	}
+
+	// 处理项目评论信息
+	err = this.DoProcessCommentFile(commentFile)
+	if err != nil {
+		return err
+	}
+
+	// 处理项目用户关系信息
+	err = this.DoProcessUserProjectRelationFile(userProjectRelationFile)
+	if err != nil {
+		return err
+	}
+
+	// 读取输入文件
+	input, err := OpenInput("projects", projectFile, &model.ProjectRecord{})
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	// 创建生成结果文件
+	output, err := os.OpenFile(outputFile, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	runtime.GOMAXPROCS(runtime.NumCPU())
+
+	jobs := make(chan Job, this.Workers)
+	done := make(chan struct{}, this.Workers)
+	result := make(chan Result, this.Workers)
+
+	// 将需要并发处理的任务添加到jobs的channel中
+	go this.AddJobs(jobs, result, input)
+
+	// 根据cpu的数量启动对应个数的goroutines从jobs争夺任务进行处理
+	for i := 0; i < this.Workers; i++ {
+		go this.DoJobs(done, jobs)
+	}
+
+	// 等待所有worker routiines的完成结果, 并将结果通知主routine
+	go this.AwaitJobDone(done, result)
+
+	return this.DoResult(result, output)
+}
@@ -0,0 +1,200 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"time"
+
+	"doraemon/model"
+	"doraemon/util"
+)
+
+func init() {
+	Register("PersonalUserRecommend", NewPersonalUserRecommendTask())
+}
+
+// PersonalUserRecommendTask recommends "people you may know" to every user:
+// the users followed by the people a user follows, ranked by Adamic-Adar
+// weighted shared follows.
+type PersonalUserRecommendTask struct {
+	*UserRecommendTask
+	UserFollowingMap map[int64]map[int64]bool // 用户关注的用户
+}
+
+func NewPersonalUserRecommendTask() *PersonalUserRecommendTask {
+	return &PersonalUserRecommendTask{
+		UserRecommendTask: NewUserRecommendTask(),
+		UserFollowingMap:  make(map[int64]map[int64]bool),
+	}
+}
+
+func (this *PersonalUserRecommendTask) DoResult(result <-chan Result, output *os.File) error {
+	var number int = 0
+	for _ = range result {
+		number += 1
+
+		if number%10000 == 0 {
+			fmt.Printf("%d\t%s\n", number, time.Now().String())
+		}
+	}
+
+	// 输入文件读取失败或跳过的行过多时不生成结果
+	if this.InputErr != nil {
+		return this.InputErr
+	}
+
+	// 由被关注信息生成关注信息
+	for followedId, followers := range this.UserRalationMap {
+		for followerId, _ := range followers {
+			if v, ok := this.UserFollowingMap[followerId]; ok {
+				v[followedId] = true
+			} else {
+				subMap := make(map[int64]bool)
+				subMap[followedId] = true
+				this.UserFollowingMap[followerId] = subMap
+			}
+		}
+	}
+
+	items := make(map[int64]interface{})
+	for userId, _ := range this.UserInfoMap {
+		scores := this.DoCalculateScore(userId)
+		if len(scores) == 0 {
+			continue
+		}
+
+		var userRecommends []model.UserRecommend
+		for k, v := range scores {
+			user, ok := this.UserInfoMap[k]
+			if !ok {
+				continue
+			}
+
+			var userRecommend model.UserRecommend
+			userRecommend.Id = k
+			userRecommend.Name = user.Name
+			userRecommend.Description = user.Description
+			userRecommend.Score = v
+
+			userRecommends = append(userRecommends, userRecommend)
+		}
+
+		if len(userRecommends) == 0 {
+			continue
+		}
+
+		util.DescByField(userRecommends, "Score")
+		if int64(len(userRecommends)) > util.MaxPersonalUserRecommendCount {
+			userRecommends = userRecommends[:util.MaxPersonalUserRecommendCount]
+		}
+
+		var personalUserRecommend model.PersonalUserRecommend
+		personalUserRecommend.UserId = userId
+		personalUserRecommend.Users = userRecommends
+
+		data, err := json.Marshal(&personalUserRecommend)
+		if err != nil {
+			continue
+		}
+
+		data = append(data, '\n')
+		_, _ = output.WriteString(string(data))
+
+		items[userId] = personalUserRecommend
+	}
+
+	// 将推荐结果写入memcached
+	return PublishResult("PersonalUserRecommend", nil, items)
+}
+
+// DoCalculateScore scores the users followed by the people userId follows.
+// Every shared follow through an intermediate user w adds 1/log(degree(w)),
+// so a follow shared through a small circle counts more than one shared
+// through a user who follows and is followed by everybody. Users already
+// followed and the user itself are left out.
+func (this *PersonalUserRecommendTask) DoCalculateScore(userId int64) map[int64]float64 {
+	scores := make(map[int64]float64)
+
+	following := this.UserFollowingMap[userId]
+	for middleId, _ := range following {
+		middleFollowing := this.UserFollowingMap[middleId]
+		if len(middleFollowing) > util.PersonalUserMaxFollowing {
+			continue
+		}
+
+		degree := len(middleFollowing) + len(this.UserRalationMap[middleId])
+		weight := 1 / math.Log(float64(degree))
+
+		for candidateId, _ := range middleFollowing {
+			if candidateId == userId || following[candidateId] {
+				continue
+			}
+
+			scores[candidateId] += weight
+		}
+	}
+
+	return scores
+}
+
+func (this *PersonalUserRecommendTask) DoDataTask(inputFiles []string, outputFile string, arg interface{}) error {
+	// 检查输入参数信息
+	if len(inputFiles) < 5 {
+		return errors.New("DoDataTask PersonalUserRecommendTask check fail, inputFiles len is not correct")
+	}
+
+	// 设置文件名称, 创意和评论信息不参与计算
+	userFile := inputFiles[0]
+	userProfileFile := inputFiles[1]
+	userRelationFile := inputFiles[4]
+
+	var err error
+	// 处理用户简介信息
+	err = this.DoProcessUserProfileFile(userProfileFile)
+	if err != nil {
+		return err
+	}
+
+	// 处理用户关注信息
+	err = this.DoProcessUserRelationFile(userRelationFile)
+	if err != nil {
+		return err
+	}
+
+	// 读取输入文件
+	input, err := OpenInput("users", userFile, &model.UserRecord{})
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	// 创建生成结果文件
+	output, err := os.OpenFile(outputFile, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	runtime.GOMAXPROCS(runtime.NumCPU())
+
+	jobs := make(chan Job, this.Workers)
+	done := make(chan struct{}, this.Workers)
+	result := make(chan Result, this.Workers)
+
+	// 将需要并发处理的任务添加到jobs的channel中
+	go this.AddJobs(jobs, result, input)
+
+	// 根据cpu的数量启动对应个数的goroutines从jobs争夺任务进行处理
+	for i := 0; i < this.Workers; i++ {
+		go this.DoJobs(done, jobs)
+	}
+
+	// 等待所有worker routiines的完成结果, 并将结果通知主routine
+	go this.AwaitJobDone(done, result)
+
+	return this.DoResult(result, output)
+}
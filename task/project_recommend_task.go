@@ -1,19 +1,18 @@
 package task
 
 import (
-	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"runtime"
-	"strconv"
-	"strings"
+	"sort"
 	"sync"
 	"time"
 
 	"doraemon/model"
+	"doraemon/modual/reader"
 	"doraemon/util"
 )
 
@@ -22,61 +21,68 @@ func init() {
 }
 
 type ProjectRecommendTask struct {
-	Workers           int
-	ProjectUserMap    map[int64]map[int64][]int64 // 项目关注/加入用户信息
-	ProjectIdeaMap    map[int64]map[int64][]int64 // 项目创意信息
-	ProjectCommentMap map[int64]map[int64][]int64 // 项目评论信息
-	ProjectTitleMap   map[int64]string            // 项目标题信息
-	Mutex             sync.Mutex
+	Workers               int
+	ProjectUserMap        map[int64]map[int64][]int64   // 项目关注/加入用户信息
+	ProjectUserWeight     map[int64]map[int64][]float64 // 项目关注/加入用户的状态权重
+	ProjectFollower       map[int64]int64               // 项目当前的关注用户数
+	ProjectFormerFollower map[int64]int64               // 项目已取消关注的用户数
+	ProjectMember         map[int64]int64               // 项目当前的成员数
+	ProjectFormerMember   map[int64]int64               // 项目已退出的成员数
+	ProjectFormerUserMap  map[int64]map[int64]bool      // 项目已取消关注或已退出的用户
+	ProjectIdeaMap        map[int64]map[int64][]int64   // 项目创意信息
+	ProjectCommentMap     map[int64]map[int64][]int64   // 项目评论信息
+	ProjectCommentWeight  map[int64]map[int64][]float64 // 项目评论的类型权重
+	ProjectInfoMap        map[int64]*model.Project      // 项目基本信息
+	InputErr              error                         // 读取任务输入文件的错误
+	Mutex                 sync.Mutex
 }
 
 func NewProjectRecommendTask() *ProjectRecommendTask {
 	return &ProjectRecommendTask{
-		Workers:           1,
-		ProjectUserMap:    make(map[int64]map[int64][]int64),
-		ProjectIdeaMap:    make(map[int64]map[int64][]int64),
-		ProjectCommentMap: make(map[int64]map[int64][]int64),
-		ProjectTitleMap:   make(map[int64]string),
+		Workers:               1,
+		ProjectUserMap:        make(map[int64]map[int64][]int64),
+		ProjectUserWeight:     make(map[int64]map[int64][]float64),
+		ProjectFollower:       make(map[int64]int64),
+		ProjectFormerFollower: make(map[int64]int64),
+		ProjectMember:         make(map[int64]int64),
+		ProjectFormerMember:   make(map[int64]int64),
+		ProjectFormerUserMap:  make(map[int64]map[int64]bool),
+		ProjectIdeaMap:        make(map[int64]map[int64][]int64),
+		ProjectCommentMap:     make(map[int64]map[int64][]int64),
+		ProjectCommentWeight:  make(map[int64]map[int64][]float64),
+		ProjectInfoMap:        make(map[int64]*model.Project),
 	}
 }
 
 func (this *ProjectRecommendTask) DoJob(job Job) {
-	// id \t title \t user_id \t created_at
-	fields := strings.Split(job.data, "\t")
-	if len(fields) != 4 {
-		return
-	}
-
-	id := fields[0]
-	title := fields[1]
-	user_id := fields[2]
-	created_at := fields[3]
-
-	if title == "" || user_id == "" || created_at == "" {
-		return
-	}
+	record := job.data.(*model.ProjectRecord)
 
-	projectId, err := strconv.ParseInt(id, 10, 64)
-	if err != nil {
-		return
-	}
+	project := &model.Project{}
+	project.Id = record.Id
+	project.Title = record.Title
+	project.UserId = record.UserId
+	project.CreatedAt = record.CreatedAt
 
 	this.Mutex.Lock()
-	this.ProjectTitleMap[projectId] = title
+	this.ProjectInfoMap[record.Id] = project
 	defer this.Mutex.Unlock()
 
 	job.result <- Result{job.data}
 }
 
-func (this *ProjectRecommendTask) AddJobs(jobs chan<- Job, result chan<- Result, input *os.File) {
-	br := bufio.NewReader(input)
+func (this *ProjectRecommendTask) AddJobs(jobs chan<- Job, result chan<- Result, input *reader.Reader) {
 	for {
-		line, err := br.ReadString('\n')
+		record := &model.ProjectRecord{}
+		err := input.Read(record)
 		if err == io.EOF {
+			// 检查被跳过的行数
+			this.InputErr = CheckInput("projects", input)
+			break
+		} else if err != nil {
+			this.InputErr = err
 			break
 		} else {
-			realLine := strings.ToLower(strings.TrimRight(line, "\n"))
-			jobs <- Job{realLine, result}
+			jobs <- Job{record, result}
 		}
 	}
 
@@ -103,7 +109,7 @@ func (this *ProjectRecommendTask) AwaitJobDone(done <-chan struct{}, result chan
 	close(result)
 }
 
-func (this *ProjectRecommendTask) DoResult(result <-chan Result, output *os.File) {
+func (this *ProjectRecommendTask) DoResult(result <-chan Result, output *os.File) error {
 	var number int = 0
 	for _ = range result {
 		number += 1
@@ -113,34 +119,138 @@ func (this *ProjectRecommendTask) DoResult(result <-chan Result, output *os.File
 		}
 	}
 
-	var projectRecommends []model.ProjectRecommend
-	minFilterTime := time.Now().AddDate(0, 0, (-1)*util.ProjectRecommendFilterDayNum).Unix()
-	for k, v := range this.ProjectTitleMap {
-		// 获取用户创意数，最近的创意数量
-		ideasCount, recentIdeasCount := this.DoCalculateCount(this.ProjectIdeaMap, k, minFilterTime)
+	// 输入文件读取失败或跳过的行过多时不生成结果
+	if this.InputErr != nil {
+		return this.InputErr
+	}
+
+	scoreConf := model.GlobalConf.ProjectRecommendScore
+	err := CheckScoreConf(scoreConf)
+	if err != nil {
+		return err
+	}
+
+	smoothingConf := model.GlobalConf.ProjectRecommendSmoothing
+	err = CheckSmoothingConf(smoothingConf)
+	if err != nil {
+		return err
+	}
 
-		// 获取用户评论数，近期的评论数量
-		commentsCount, recentCommentsCount := this.DoCalculateCount(this.ProjectCommentMap, k, minFilterTime)
+	newProjectConf := model.GlobalConf.ProjectRecommendNewProject
+	err = CheckNewProjectConf(newProjectConf)
+	if err != nil {
+		return err
+	}
 
-		// 获取用户关注/参加数量，最近的用户关注/参加数量
-		usersCount, recentUsersCount := this.DoCalculateCount(this.ProjectUserMap, k, minFilterTime)
+	userCapConf := model.GlobalConf.ProjectRecommendUserCap
+	err = CheckUserCapConf(userCapConf)
+	if err != nil {
+		return err
+	}
 
-		// 计算项目得分
-		score := util.ProjectRecommendBasicPercent*(float64)(ideasCount+commentsCount+usersCount) + util.ProjectRecommendActionPercent*(float64)(recentIdeasCount+recentCommentsCount+recentUsersCount)
+	diversityConf := model.GlobalConf.ProjectRecommendDiversity
+	_, ok := ProjectSimilarities[diversityConf.Similarity]
+	err = CheckDiversityConf(diversityConf, ok)
+	if err != nil {
+		return err
+	}
 
-		// data := fmt.Sprintf("%d\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%f\n", k, v, ideasCount, recentIdeasCount, commentsCount, recentCommentsCount, usersCount, recentUsersCount, score)
-		// fmt.Println(data)
+	var projectRecommends []model.ProjectRecommend
+	items := make(map[int64]interface{})
+	verbose := model.GlobalConf.VerboseOutput
+	now := time.Now().Unix()
+	minFilterTime := time.Now().AddDate(0, 0, (-1)*util.ProjectRecommendFilterDayNum).Unix()
+	for k, v := range this.ProjectInfoMap {
+		var score float64
+		var features []model.ScoreFeature
+		if scoreConf.Mode == ScoreModeWindow {
+			// 获取用户创意数，最近的创意数量
+			ideasCount, recentIdeasCount := this.DoCalculateCount(this.ProjectIdeaMap, nil, k, minFilterTime, userCapConf)
+
+			// 获取用户评论数，近期的评论数量
+			commentsCount, recentCommentsCount := this.DoCalculateCount(this.ProjectCommentMap, this.ProjectCommentWeight, k, minFilterTime, userCapConf)
+
+			// 获取用户关注/参加数量，最近的用户关注/参加数量
+			usersCount, recentUsersCount := this.DoCalculateCount(this.ProjectUserMap, this.ProjectUserWeight, k, minFilterTime, userCapConf)
+
+			// 计算项目得分
+			score = util.ProjectRecommendBasicPercent*(ideasCount+commentsCount+usersCount) + util.ProjectRecommendActionPercent*(recentIdeasCount+recentCommentsCount+recentUsersCount)
+
+			// 记录得分明细
+			if verbose {
+				features = AddFeature(features, "ideas", ideasCount, util.ProjectRecommendBasicPercent)
+				features = AddFeature(features, "recent_ideas", recentIdeasCount, util.ProjectRecommendActionPercent)
+				features = AddFeature(features, "comments", commentsCount, util.ProjectRecommendBasicPercent)
+				features = AddFeature(features, "recent_comments", recentCommentsCount, util.ProjectRecommendActionPercent)
+				features = AddFeature(features, "users", usersCount, util.ProjectRecommendBasicPercent)
+				features = AddFeature(features, "recent_users", recentUsersCount, util.ProjectRecommendActionPercent)
+			}
+		} else {
+			// 按时间衰减后的创意、评论、关注/参加数量计算项目得分
+			ideasWeight := this.DoCalculateWeight(this.ProjectIdeaMap, nil, k, now, scoreConf, userCapConf)
+			commentsWeight := this.DoCalculateWeight(this.ProjectCommentMap, this.ProjectCommentWeight, k, now, scoreConf, userCapConf)
+			usersWeight := this.DoCalculateWeight(this.ProjectUserMap, this.ProjectUserWeight, k, now, scoreConf, userCapConf)
+			score = ideasWeight + commentsWeight + usersWeight
+
+			// 记录得分明细
+			if verbose {
+				features = AddFeature(features, "ideas", ideasWeight, 1)
+				features = AddFeature(features, "comments", commentsWeight, 1)
+				features = AddFeature(features, "users", usersWeight, 1)
+			}
+		}
 
 		var projectRecommend model.ProjectRecommend
 		projectRecommend.Id = k
-		projectRecommend.Title = v
+		projectRecommend.Title = v.Title
+		projectRecommend.UserId = v.UserId
+		projectRecommend.CreatedAt = v.CreatedAt
+		projectRecommend.Users = int64(len(this.ProjectUserMap[k]))
+		projectRecommend.Followers = this.ProjectFollower[k]
+		projectRecommend.FormerFollowers = this.ProjectFormerFollower[k]
+		projectRecommend.Members = this.ProjectMember[k]
+		projectRecommend.FormerMembers = this.ProjectFormerMember[k]
 		projectRecommend.Score = score
+		projectRecommend.Features = features
 
 		projectRecommends = append(projectRecommends, projectRecommend)
 	}
 
+	// 平滑项目得分
+	rawScores := make([]float64, len(projectRecommends))
+	for i, v := range projectRecommends {
+		rawScores[i] = v.Score
+	}
+
+	this.DoSmoothScore(projectRecommends, smoothingConf, userCapConf)
+
+	if verbose && smoothingConf.Mode != SmoothingModeNone {
+		for i, v := range projectRecommends {
+			projectRecommends[i].Features = AddFeature(v.Features, "smoothing", v.Score-rawScores[i], 1)
+		}
+	}
+
+	// 提升新项目得分
+	for i, v := range projectRecommends {
+		boost := NewProjectBoost(newProjectConf, now, v.CreatedAt)
+		projectRecommends[i].Score = v.Score * boost
+
+		if verbose && boost != 1 {
+			projectRecommends[i].Features = AddFactor(v.Features, "new_project_boost", boost, v.Score)
+		}
+	}
+
+	for _, v := range projectRecommends {
+		items[v.Id] = v
+	}
+
 	util.DescByField(projectRecommends, "Score")
-	projectRecommends = projectRecommends[:util.MaxProjectRecommendCount]
+
+	// 按多样性重排推荐结果
+	projectRecommends = this.DoDiversify(projectRecommends, diversityConf)
+
+	// 为新项目保留推荐位置
+	projectRecommends = this.DoReserveNewProjects(projectRecommends, newProjectConf, now)
 
 	for _, v := range projectRecommends {
 		data, err := json.Marshal(&v)
@@ -151,201 +261,432 @@ func (this *ProjectRecommendTask) DoResult(result <-chan Result, output *os.File
 		data = append(data, '\n')
 		_, _ = output.WriteString(string(data))
 	}
+
+	// 将推荐结果写入memcached
+	return PublishResult("ProjectRecommend", projectRecommends, items)
 }
 
 func (this *ProjectRecommendTask) DoProcessIdeaFile(inputFile string) error {
-	input, err := os.OpenFile(inputFile, os.O_RDONLY, 0)
+	input, err := OpenInput("ideas", inputFile, &model.IdeaRecord{})
 	if err != nil {
 		return err
 	}
 	defer input.Close()
 
-	br := bufio.NewReader(input)
 	for {
-		line, err := br.ReadString('\n')
+		record := &model.IdeaRecord{}
+		err := input.Read(record)
 		if err == io.EOF {
 			break
+		} else if err != nil {
+			return err
+		}
+
+		if v, ok := this.ProjectIdeaMap[record.ProjectId]; ok {
+			v[record.UserId] = append(v[record.UserId], record.CreatedAt)
 		} else {
-			realLine := strings.ToLower(strings.TrimRight(line, "\n"))
+			var times []int64
+			times = append(times, record.CreatedAt)
+			subMap := make(map[int64][]int64)
+			subMap[record.UserId] = times
+			this.ProjectIdeaMap[record.ProjectId] = subMap
+		}
+	}
 
-			// id \t project_id \t user_id \t created_at
-			fields := strings.Split(realLine, "\t")
-			if len(fields) != 4 {
-				continue
-			}
+	// 检查被跳过的行数
+	err = CheckInput("ideas", input)
+	if err != nil {
+		return err
+	}
 
-			projectId := fields[1]
-			userId := fields[2]
-			createdAt := fields[3]
+	return nil
+}
 
-			if projectId == "" || userId == "" || createdAt == "" {
-				continue
-			}
+func (this *ProjectRecommendTask) DoProcessCommentFile(inputFile string) error {
+	typeWeights, err := CheckCommentTypeConf(model.GlobalConf.CommentTypes)
+	if err != nil {
+		return err
+	}
 
-			var err error
-			projectIdNum, err := strconv.ParseInt(projectId, 10, 64)
-			if err != nil {
-				continue
-			}
+	input, err := OpenInput("comments", inputFile, &model.CommentRecord{})
+	if err != nil {
+		return err
+	}
+	defer input.Close()
 
-			userIdNum, err := strconv.ParseInt(userId, 10, 64)
-			if err != nil {
-				continue
-			}
+	for {
+		record := &model.CommentRecord{}
+		err := input.Read(record)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
 
-			createdAtTime := util.ParseDateTime(createdAt).Unix()
+		weight := CommentWeight(typeWeights, record.CommentableType)
 
-			if v, ok := this.ProjectIdeaMap[projectIdNum]; ok {
-				v[userIdNum] = append(v[userIdNum], createdAtTime)
-			} else {
-				var times []int64
-				times = append(times, createdAtTime)
-				subMap := make(map[int64][]int64)
-				subMap[userIdNum] = times
-				this.ProjectIdeaMap[projectIdNum] = subMap
-			}
+		if v, ok := this.ProjectCommentMap[record.ProjectId]; ok {
+			v[record.UserId] = append(v[record.UserId], record.CreatedAt)
+			this.ProjectCommentWeight[record.ProjectId][record.UserId] = append(this.ProjectCommentWeight[record.ProjectId][record.UserId], weight)
+		} else {
+			var times []int64
+			times = append(times, record.CreatedAt)
+			subMap := make(map[int64][]int64)
+			subMap[record.UserId] = times
+			this.ProjectCommentMap[record.ProjectId] = subMap
+
+			var weights []float64
+			weights = append(weights, weight)
+			weightMap := make(map[int64][]float64)
+			weightMap[record.UserId] = weights
+			this.ProjectCommentWeight[record.ProjectId] = weightMap
 		}
 	}
 
+	// 检查被跳过的行数
+	err = CheckInput("comments", input)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func (this *ProjectRecommendTask) DoProcessCommentFile(inputFile string) error {
-	input, err := os.OpenFile(inputFile, os.O_RDONLY, 0)
+// DoProcessUserProjectRelationFile replays the status changes of every user
+// in every project in time order, the follow and the member relation each on
+// its own. A user whose latest status in a relation has a positive weight is
+// a current follower or member, counted with the time and weight of that
+// status; a user who had such a status before is a former one. A user who
+// joins and later unfollows is still a current member.
+func (this *ProjectRecommendTask) DoProcessUserProjectRelationFile(inputFile string) error {
+	statuses, err := CheckRelationStatusConf(model.GlobalConf.ProjectRecommendRelationStatus)
+	if err != nil {
+		return err
+	}
+
+	input, err := OpenInput("user_projects", inputFile, &model.UserProjectRecord{})
 	if err != nil {
 		return err
 	}
 	defer input.Close()
 
-	br := bufio.NewReader(input)
+	// 项目中用户的状态变化
+	relationMap := make(map[int64]map[int64][]model.ProjectRelation)
+
 	for {
-		line, err := br.ReadString('\n')
+		record := &model.UserProjectRecord{}
+		err := input.Read(record)
 		if err == io.EOF {
 			break
+		} else if err != nil {
+			return err
+		}
+
+		// 只处理配置中的状态
+		status, ok := statuses[record.Status]
+		if !ok {
+			continue
+		}
+
+		relation := model.ProjectRelation{Status: record.Status, Relation: status.Relation, Weight: status.Weight, CreatedAt: record.CreatedAt}
+
+		if v, ok := relationMap[record.ProjectId]; ok {
+			v[record.UserId] = append(v[record.UserId], relation)
 		} else {
-			realLine := strings.ToLower(strings.TrimRight(line, "\n"))
+			var relations []model.ProjectRelation
+			relations = append(relations, relation)
+			subMap := make(map[int64][]model.ProjectRelation)
+			subMap[record.UserId] = relations
+			relationMap[record.ProjectId] = subMap
+		}
+	}
 
-			// id \t project_id \t user_id \t commentable_id \t commentable_type \t created_at
-			fields := strings.Split(realLine, "\t")
-			if len(fields) != 6 {
-				continue
-			}
+	// 检查被跳过的行数
+	err = CheckInput("user_projects", input)
+	if err != nil {
+		return err
+	}
 
-			projectId := fields[1]
-			userId := fields[2]
-			createdAt := fields[5]
+	for projectId, users := range relationMap {
+		for userId, relations := range users {
+			// 按时间顺序处理状态变化，关注和成员关系分别以最后的状态为准
+			sort.SliceStable(relations, func(i, j int) bool { return relations[i].CreatedAt < relations[j].CreatedAt })
 
-			if projectId == "" || userId == "" || createdAt == "" {
-				continue
-			}
+			this.DoReplayRelation(projectId, userId, relations, RelationFollow, this.ProjectFollower, this.ProjectFormerFollower)
+			this.DoReplayRelation(projectId, userId, relations, RelationMember, this.ProjectMember, this.ProjectFormerMember)
+		}
+	}
+
+	return nil
+}
 
-			var err error
-			projectIdNum, err := strconv.ParseInt(projectId, 10, 64)
-			if err != nil {
-				continue
+// DoReplayRelation finds the latest status of userId in the relation kind of
+// project projectId from the time ordered relations. A current follower or
+// member is added to ProjectUserMap and counted in current, a former one is
+// added to ProjectFormerUserMap and counted in former.
+func (this *ProjectRecommendTask) DoReplayRelation(projectId int64, userId int64, relations []model.ProjectRelation, kind string, current map[int64]int64, former map[int64]int64) {
+	var latest *model.ProjectRelation
+	var before bool
+	for i, v := range relations {
+		if v.Relation != kind {
+			continue
+		}
+
+		if latest != nil && latest.Weight > 0 {
+			before = true
+		}
+		latest = &relations[i]
+	}
+
+	if latest == nil {
+		return
+	}
+
+	if latest.Weight <= 0 {
+		if before {
+			former[projectId] += 1
+
+			if _, ok := this.ProjectFormerUserMap[projectId]; !ok {
+				this.ProjectFormerUserMap[projectId] = make(map[int64]bool)
 			}
+			this.ProjectFormerUserMap[projectId][userId] = true
+		}
+		return
+	}
+
+	current[projectId] += 1
+
+	if _, ok := this.ProjectUserMap[projectId]; !ok {
+		this.ProjectUserMap[projectId] = make(map[int64][]int64)
+		this.ProjectUserWeight[projectId] = make(map[int64][]float64)
+	}
+	this.ProjectUserMap[projectId][userId] = append(this.ProjectUserMap[projectId][userId], latest.CreatedAt)
+	this.ProjectUserWeight[projectId][userId] = append(this.ProjectUserWeight[projectId][userId], latest.Weight)
+}
+
+// DoCalculateCount returns the number of events of key and the number of
+// those since minFilterTime, each event counted with its weight in weights,
+// if any, and each user's events capped and dampened as selected by
+// userCapConf. The events of the project owner count OwnerWeight times.
+func (this *ProjectRecommendTask) DoCalculateCount(data map[int64]map[int64][]int64, weights map[int64]map[int64][]float64, key int64, minFilterTime int64, userCapConf model.UserCapConf) (float64, float64) {
+	var countA, countB float64
 
-			userIdNum, err := strconv.ParseInt(userId, 10, 64)
-			if err != nil {
-				continue
+	if v, ok := data[key]; ok {
+		for userId, vv := range v {
+			var userCountA, userCountB float64
+			for _, i := range CapUserActions(userCapConf, vv) {
+				weight := ActionWeight(weights[key][userId], i)
+				userCountA += weight
+
+				if vv[i] >= minFilterTime {
+					userCountB += weight
+				}
 			}
 
-			createdAtTime := util.ParseDateTime(createdAt).Unix()
+			ownerWeight := this.DoOwnerWeight(key, userId, userCapConf)
+			countA += ownerWeight * DampenUserActions(userCapConf, userCountA)
+			countB += ownerWeight * DampenUserActions(userCapConf, userCountB)
+		}
+	}
+
+	return countA, countB
+}
+
+// DoCalculateWeight sums the weights of the events of key, each event
+// weighted by its age as selected by scoreConf and by its weight in weights,
+// if any, and each user's events capped and dampened as selected by
+// userCapConf. The events of the project owner count OwnerWeight times.
+func (this *ProjectRecommendTask) DoCalculateWeight(data map[int64]map[int64][]int64, weights map[int64]map[int64][]float64, key int64, now int64, scoreConf model.ScoreConf, userCapConf model.UserCapConf) float64 {
+	var weight float64
 
-			if v, ok := this.ProjectCommentMap[projectIdNum]; ok {
-				v[userIdNum] = append(v[userIdNum], createdAtTime)
-			} else {
-				var times []int64
-				times = append(times, createdAtTime)
-				subMap := make(map[int64][]int64)
-				subMap[userIdNum] = times
-				this.ProjectCommentMap[projectIdNum] = subMap
+	if v, ok := data[key]; ok {
+		for userId, vv := range v {
+			var userWeight float64
+			for _, i := range CapUserActions(userCapConf, vv) {
+				userWeight += ActionWeight(weights[key][userId], i) * EventWeight(scoreConf, now, vv[i])
 			}
+
+			weight += this.DoOwnerWeight(key, userId, userCapConf) * DampenUserActions(userCapConf, userWeight)
 		}
 	}
 
-	return nil
+	return weight
 }
 
-func (this *ProjectRecommendTask) DoProcessUserProjectRelationFile(inputFile string) error {
-	input, err := os.OpenFile(inputFile, os.O_RDONLY, 0)
-	if err != nil {
-		return err
+// DoOwnerWeight returns the weight of the activity of userId in project key,
+// so that owners cannot push their own projects up.
+func (this *ProjectRecommendTask) DoOwnerWeight(key int64, userId int64, userCapConf model.UserCapConf) float64 {
+	if v, ok := this.ProjectInfoMap[key]; ok && v.UserId == userId {
+		return userCapConf.OwnerWeight
 	}
-	defer input.Close()
 
-	br := bufio.NewReader(input)
-	for {
-		line, err := br.ReadString('\n')
-		if err == io.EOF {
-			break
-		} else {
-			realLine := strings.ToLower(strings.TrimRight(line, "\n"))
+	return 1
+}
 
-			// id \t project_id \t user_id \t status \t created_at
-			fields := strings.Split(realLine, "\t")
-			if len(fields) != 5 {
-				continue
-			}
+// DoSmoothScore replaces the raw scores of projectRecommends, so that a few
+// old projects with huge counts do not bury everything else.
+//
+// In bayes mode the score per follower of a project is shrunk towards the
+// score per follower of all projects:
+//
+//	score = (raw + C*m) / (followers + C)
+//
+// where m is the sum of all raw scores over the sum of all followers, and C
+// is PriorWeight or, if that is 0, the average number of followers.
+//
+// In wilson mode the score is the Wilson lower bound of the share of a
+// project's users who also create ideas or comments in it.
+//
+// In both modes the project owner counts OwnerWeight times, like in the raw
+// scores.
+func (this *ProjectRecommendTask) DoSmoothScore(projectRecommends []model.ProjectRecommend, smoothingConf model.SmoothingConf, userCapConf model.UserCapConf) {
+	switch smoothingConf.Mode {
+	case SmoothingModeBayes:
+		var scoreSum, userSum float64
+		for _, v := range projectRecommends {
+			scoreSum += v.Score
+			userSum += this.DoCountUsers(this.ProjectUserMap[v.Id], v.Id, userCapConf)
+		}
 
-			projectId := fields[1]
-			userId := fields[2]
-			status := fields[3]
-			createdAt := fields[4]
+		if userSum == 0 {
+			return
+		}
 
-			if projectId == "" || userId == "" || createdAt == "" {
-				continue
-			}
+		priorMean := scoreSum / userSum
+		priorWeight := smoothingConf.PriorWeight
+		if priorWeight == 0 {
+			priorWeight = userSum / float64(len(projectRecommends))
+		}
 
-			// 查看是否为关注/加入状态
-			if status != "follow" && status != "join" {
-				continue
-			}
+		for i, v := range projectRecommends {
+			userCount := this.DoCountUsers(this.ProjectUserMap[v.Id], v.Id, userCapConf)
+			projectRecommends[i].Score = util.BayesianAverage(v.Score, userCount, priorMean, priorWeight)
+		}
 
-			var err error
-			projectIdNum, err := strconv.ParseInt(projectId, 10, 64)
-			if err != nil {
-				continue
+	case SmoothingModeWilson:
+		for i, v := range projectRecommends {
+			engaged := make(map[int64]bool)
+			for userId, _ := range this.ProjectIdeaMap[v.Id] {
+				engaged[userId] = true
 			}
-
-			userIdNum, err := strconv.ParseInt(userId, 10, 64)
-			if err != nil {
-				continue
+			for userId, _ := range this.ProjectCommentMap[v.Id] {
+				engaged[userId] = true
 			}
 
-			createdAtTime := util.ParseDateTime(createdAt).Unix()
+			var positive, total float64
+			for userId, _ := range engaged {
+				positive += this.DoOwnerWeight(v.Id, userId, userCapConf)
+			}
 
-			if v, ok := this.ProjectUserMap[projectIdNum]; ok {
-				v[userIdNum] = append(v[userIdNum], createdAtTime)
-			} else {
-				var times []int64
-				times = append(times, createdAtTime)
-				subMap := make(map[int64][]int64)
-				subMap[userIdNum] = times
-				this.ProjectUserMap[projectIdNum] = subMap
+			total = positive
+			for userId, _ := range this.ProjectUserMap[v.Id] {
+				if !engaged[userId] {
+					total += this.DoOwnerWeight(v.Id, userId, userCapConf)
+				}
 			}
+
+			projectRecommends[i].Score = util.WilsonLowerBound(positive, total, smoothingConf.Z)
 		}
 	}
+}
 
-	return nil
+// DoCountUsers returns the number of users of project key, the project owner
+// counted OwnerWeight times.
+func (this *ProjectRecommendTask) DoCountUsers(users map[int64][]int64, key int64, userCapConf model.UserCapConf) float64 {
+	var count float64
+	for userId, _ := range users {
+		count += this.DoOwnerWeight(key, userId, userCapConf)
+	}
+
+	return count
 }
 
-func (this *ProjectRecommendTask) DoCalculateCount(data map[int64]map[int64][]int64, key int64, minFilterTime int64) (int64, int64) {
-	var countA, countB int64
+// DoDiversify re-ranks the first CandidateCount entries of the sorted
+// projectRecommends, so that similar projects, e.g. several projects of the
+// same owner, do not crowd out everything else.
+func (this *ProjectRecommendTask) DoDiversify(projectRecommends []model.ProjectRecommend, diversityConf model.DiversityConf) []model.ProjectRecommend {
+	if diversityConf.Mode == DiversityModeNone {
+		return projectRecommends
+	}
+
+	count := diversityConf.CandidateCount
+	if count > len(projectRecommends) {
+		count = len(projectRecommends)
+	}
 
-	if v, ok := data[key]; ok {
-		for _, vv := range v {
-			for _, vvv := range vv {
-				countA += 1
+	candidates := projectRecommends[:count]
+	relevance := make([]float64, count)
+	for i, v := range candidates {
+		relevance[i] = v.Score
+	}
 
-				if vvv >= minFilterTime {
-					countB += 1
-				}
-			}
+	similarity := ProjectSimilarities[diversityConf.Similarity]
+	order := Diversify(diversityConf, relevance, func(i, j int) float64 {
+		return similarity(this, this.ProjectInfoMap[candidates[i].Id], this.ProjectInfoMap[candidates[j].Id])
+	})
+
+	ret := make([]model.ProjectRecommend, 0, len(projectRecommends))
+	for _, i := range order {
+		ret = append(ret, candidates[i])
+	}
+
+	return append(ret, projectRecommends[count:]...)
+}
+
+// DoReserveNewProjects cuts the sorted projectRecommends down to
+// util.MaxProjectRecommendCount entries, of which up to Slots are the best
+// projects created in the last MaxAgeDays days that did not make it into
+// the list on their own. The kept projects stay in the order of
+// projectRecommends, so a reserved project is never placed below one with a
+// lower score.
+func (this *ProjectRecommendTask) DoReserveNewProjects(projectRecommends []model.ProjectRecommend, newProjectConf model.NewProjectConf, now int64) []model.ProjectRecommend {
+	maxCount := int(util.MaxProjectRecommendCount)
+	if len(projectRecommends) <= maxCount {
+		return projectRecommends
+	}
+
+	slots := newProjectConf.Slots
+	if slots > maxCount {
+		slots = maxCount
+	}
+
+	minCreatedAt := now - int64(newProjectConf.MaxAgeDays*86400)
+	rest := projectRecommends[maxCount-slots:]
+	selected := make([]bool, len(rest))
+	var count int
+	for i, v := range rest {
+		if count >= slots {
+			break
+		}
+
+		if v.CreatedAt >= minCreatedAt {
+			selected[i] = true
+			count += 1
 		}
 	}
 
-	return countA, countB
+	// 新项目不足时由其他项目补足
+	for i, _ := range rest {
+		if count >= slots {
+			break
+		}
+
+		if !selected[i] {
+			selected[i] = true
+			count += 1
+		}
+	}
+
+	// 按原有顺序合并保留的项目
+	var ret []model.ProjectRecommend
+	ret = append(ret, projectRecommends[:maxCount-slots]...)
+	for i, v := range rest {
+		if selected[i] {
+			ret = append(ret, v)
+		}
+	}
+
+	return ret
 }
 
 func (this *ProjectRecommendTask) DoDataTask(inputFiles []string, outputFile string, arg interface{}) error {
@@ -380,7 +721,7 @@ func (this *ProjectRecommendTask) DoDataTask(inputFiles []string, outputFile str
 	}
 
 	// 读取输入文件
-	input, err := os.OpenFile(projectFile, os.O_RDONLY, 0)
+	input, err := OpenInput("projects", projectFile, &model.ProjectRecord{})
 	if err != nil {
 		return err
 	}
@@ -410,6 +751,5 @@ func (this *ProjectRecommendTask) DoDataTask(inputFiles []string, outputFile str
 	// 等待所有worker routiines的完成结果, 并将结果通知主routine
 	go this.AwaitJobDone(done, result)
 
-	this.DoResult(result, output)
-	return nil
+	return this.DoResult(result, output)
 }
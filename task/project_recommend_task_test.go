@@ -0,0 +1,168 @@
+package task
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"doraemon/model"
+	"doraemon/util"
+)
+
+func TestDoProcessUserProjectRelationFile(t *testing.T) {
+	tests := []struct {
+		name            string
+		lines           string
+		followers       int64
+		formerFollowers int64
+		members         int64
+		formerMembers   int64
+		users           map[int64][]int64 // 当前用户及其计入的时间
+		formerUsers     map[int64]bool
+	}{
+		{
+			name:      "follow",
+			lines:     "1\t1\t10\tfollow\t2013-01-01 10:00:00\n",
+			followers: 1,
+			users:     map[int64][]int64{10: {1357005600}},
+		},
+		{
+			name:            "follow unfollow",
+			lines:           "1\t1\t10\tfollow\t2013-01-01 10:00:00\n2\t1\t10\tunfollow\t2013-01-02 10:00:00\n",
+			formerFollowers: 1,
+			formerUsers:     map[int64]bool{10: true},
+		},
+		{
+			name:      "follow unfollow follow",
+			lines:     "3\t1\t10\tfollow\t2013-01-03 10:00:00\n1\t1\t10\tfollow\t2013-01-01 10:00:00\n2\t1\t10\tunfollow\t2013-01-02 10:00:00\n",
+			followers: 1,
+			users:     map[int64][]int64{10: {1357178400}},
+		},
+		{
+			name:    "join unfollow",
+			lines:   "1\t1\t10\tjoin\t2013-01-01 10:00:00\n2\t1\t10\tunfollow\t2013-01-02 10:00:00\n",
+			members: 1,
+			users:   map[int64][]int64{10: {1357005600}},
+		},
+		{
+			name:          "follow join leave",
+			lines:         "1\t1\t10\tfollow\t2013-01-01 10:00:00\n2\t1\t10\tjoin\t2013-01-02 10:00:00\n3\t1\t10\tleave\t2013-01-03 10:00:00\n",
+			followers:     1,
+			formerMembers: 1,
+			users:         map[int64][]int64{10: {1357005600}},
+			formerUsers:   map[int64]bool{10: true},
+		},
+		{
+			name:            "same time unfollow last",
+			lines:           "1\t1\t10\tfollow\t2013-01-01 10:00:00\n2\t1\t10\tunfollow\t2013-01-01 10:00:00\n",
+			formerFollowers: 1,
+			formerUsers:     map[int64]bool{10: true},
+		},
+		{
+			name:      "same time follow last",
+			lines:     "2\t1\t10\tunfollow\t2013-01-01 10:00:00\n1\t1\t10\tfollow\t2013-01-01 10:00:00\n",
+			followers: 1,
+			users:     map[int64][]int64{10: {1357005600}},
+		},
+		{
+			name:  "unfollow only",
+			lines: "1\t1\t10\tunfollow\t2013-01-01 10:00:00\n2\t1\t11\tunknown\t2013-01-01 10:00:00\n",
+		},
+		{
+			name:            "users",
+			lines:           "1\t1\t10\tfollow\t2013-01-01 10:00:00\n2\t1\t11\tjoin\t2013-01-01 10:00:00\n3\t1\t12\tfollow\t2013-01-01 10:00:00\n4\t1\t12\tunfollow\t2013-01-02 10:00:00\n",
+			followers:       1,
+			formerFollowers: 1,
+			members:         1,
+			users:           map[int64][]int64{10: {1357005600}, 11: {1357005600}},
+			formerUsers:     map[int64]bool{12: true},
+		},
+	}
+
+	for _, v := range tests {
+		file, err := ioutil.TempFile("", "user_projects")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(file.Name())
+
+		_, err = file.WriteString(v.lines)
+		file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		task := NewProjectRecommendTask()
+		err = task.DoProcessUserProjectRelationFile(file.Name())
+		if err != nil {
+			t.Errorf("%s: %v", v.name, err)
+			continue
+		}
+
+		if task.ProjectFollower[1] != v.followers || task.ProjectFormerFollower[1] != v.formerFollowers || task.ProjectMember[1] != v.members || task.ProjectFormerMember[1] != v.formerMembers {
+			t.Errorf("%s: got followers %d/%d members %d/%d, want %d/%d %d/%d", v.name,
+				task.ProjectFollower[1], task.ProjectFormerFollower[1], task.ProjectMember[1], task.ProjectFormerMember[1],
+				v.followers, v.formerFollowers, v.members, v.formerMembers)
+		}
+
+		users := task.ProjectUserMap[1]
+		if len(users) != len(v.users) || (len(v.users) > 0 && !reflect.DeepEqual(users, v.users)) {
+			t.Errorf("%s: got users %v, want %v", v.name, users, v.users)
+		}
+
+		formerUsers := task.ProjectFormerUserMap[1]
+		if len(formerUsers) != len(v.formerUsers) || (len(v.formerUsers) > 0 && !reflect.DeepEqual(formerUsers, v.formerUsers)) {
+			t.Errorf("%s: got former users %v, want %v", v.name, formerUsers, v.formerUsers)
+		}
+	}
+}
+
+func TestDoReserveNewProjects(t *testing.T) {
+	maxCount := util.MaxProjectRecommendCount
+	util.MaxProjectRecommendCount = 5
+	defer func() { util.MaxProjectRecommendCount = maxCount }()
+
+	now := int64(1357005600)
+	tests := []struct {
+		name  string
+		count int
+		slots int
+		new   []int64 // 最近创建的项目
+		want  []int64
+	}{
+		{"fewer than the max", 3, 2, []int64{3}, []int64{1, 2, 3}},
+		{"as many as the max", 5, 2, []int64{5}, []int64{1, 2, 3, 4, 5}},
+		{"no slots", 8, 0, []int64{7}, []int64{1, 2, 3, 4, 5}},
+		{"reserved", 8, 2, []int64{6, 8}, []int64{1, 2, 3, 6, 8}},
+		{"reserved in list order", 8, 2, []int64{8, 4}, []int64{1, 2, 3, 4, 8}},
+		{"not enough new projects", 8, 3, []int64{7}, []int64{1, 2, 3, 4, 7}},
+		{"no new projects", 8, 2, nil, []int64{1, 2, 3, 4, 5}},
+		{"new projects in the top part", 8, 2, []int64{1, 2, 7}, []int64{1, 2, 3, 4, 7}},
+		{"slots larger than the max", 8, 10, []int64{6, 7}, []int64{1, 2, 3, 6, 7}},
+		{"slots larger than the list", 6, 10, []int64{6}, []int64{1, 2, 3, 4, 6}},
+	}
+
+	for _, v := range tests {
+		var projectRecommends []model.ProjectRecommend
+		for i := 1; i <= v.count; i++ {
+			projectRecommend := model.ProjectRecommend{Id: int64(i), CreatedAt: now - 100*86400, Score: float64(100 - i)}
+			for _, id := range v.new {
+				if id == int64(i) {
+					projectRecommend.CreatedAt = now - 86400
+				}
+			}
+			projectRecommends = append(projectRecommends, projectRecommend)
+		}
+
+		conf := model.NewProjectConf{Slots: v.slots, MaxAgeDays: 30}
+		var ids []int64
+		for _, projectRecommend := range NewProjectRecommendTask().DoReserveNewProjects(projectRecommends, conf, now) {
+			ids = append(ids, projectRecommend.Id)
+		}
+
+		if !reflect.DeepEqual(ids, v.want) {
+			t.Errorf("%s: got %v, want %v", v.name, ids, v.want)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package task
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"doraemon/model"
+	"doraemon/util"
+)
+
+const (
+	ScoreModeWindow  = "window"  // 近期与全部次数按比例加权
+	ScoreModeDecay   = "decay"   // 按半衰期指数衰减
+	ScoreModeGravity = "gravity" // 按HN式重力衰减
+)
+
+// CheckScoreConf reports whether conf names a known mode with usable
+// parameters.
+func CheckScoreConf(conf model.ScoreConf) error {
+	switch conf.Mode {
+	case ScoreModeWindow:
+	case ScoreModeDecay:
+		if conf.HalfLifeDays <= 0 {
+			return fmt.Errorf("CheckScoreConf check fail, HalfLifeDays %v must be positive", conf.HalfLifeDays)
+		}
+	case ScoreModeGravity:
+		if conf.Gravity <= 0 {
+			return fmt.Errorf("CheckScoreConf check fail, Gravity %v must be positive", conf.Gravity)
+		}
+	default:
+		return fmt.Errorf("CheckScoreConf check fail, unknown Mode %q", conf.Mode)
+	}
+
+	return nil
+}
+
+// EventWeight returns the weight of an event created at createdAt under a
+// decay or gravity conf. In window mode every event weighs 1.
+func EventWeight(conf model.ScoreConf, now int64, createdAt int64) float64 {
+	switch conf.Mode {
+	case ScoreModeDecay:
+		return util.DecayWeight(now, createdAt, conf.HalfLifeDays*86400)
+	case ScoreModeGravity:
+		return util.GravityWeight(now, createdAt, conf.Gravity)
+	}
+
+	return 1
+}
+
+const (
+	SmoothingModeNone   = "none"   // 不平滑
+	SmoothingModeBayes  = "bayes"  // 贝叶斯平均
+	SmoothingModeWilson = "wilson" // Wilson置信下限
+)
+
+// CheckSmoothingConf reports whether conf names a known mode with usable
+// parameters.
+func CheckSmoothingConf(conf model.SmoothingConf) error {
+	switch conf.Mode {
+	case SmoothingModeNone:
+	case SmoothingModeBayes:
+		if conf.PriorWeight < 0 {
+			return fmt.Errorf("CheckSmoothingConf check fail, PriorWeight %v must not be negative", conf.PriorWeight)
+		}
+	case SmoothingModeWilson:
+		if conf.Z <= 0 {
+			return fmt.Errorf("CheckSmoothingConf check fail, Z %v must be positive", conf.Z)
+		}
+	default:
+		return fmt.Errorf("CheckSmoothingConf check fail, unknown Mode %q", conf.Mode)
+	}
+
+	return nil
+}
+
+const (
+	BoostCurveExp    = "exp"    // 按半衰期指数衰减
+	BoostCurveLinear = "linear" // 线性衰减
+)
+
+// CheckNewProjectConf reports whether conf names a known curve with usable
+// parameters.
+func CheckNewProjectConf(conf model.NewProjectConf) error {
+	if conf.Boost < 0 {
+		return fmt.Errorf("CheckNewProjectConf check fail, Boost %v must not be negative", conf.Boost)
+	}
+
+	if conf.Boost > 0 && conf.BoostDays <= 0 {
+		return fmt.Errorf("CheckNewProjectConf check fail, BoostDays %v must be positive", conf.BoostDays)
+	}
+
+	if conf.Curve != BoostCurveExp && conf.Curve != BoostCurveLinear {
+		return fmt.Errorf("CheckNewProjectConf check fail, unknown Curve %q", conf.Curve)
+	}
+
+	if conf.Slots < 0 {
+		return fmt.Errorf("CheckNewProjectConf check fail, Slots %v must not be negative", conf.Slots)
+	}
+
+	return nil
+}
+
+// NewProjectBoost returns the factor the score of a project created at
+// createdAt is multiplied with: 1+Boost for a project created just now,
+// falling towards 1 along the configured curve.
+func NewProjectBoost(conf model.NewProjectConf, now int64, createdAt int64) float64 {
+	if conf.Boost == 0 {
+		return 1
+	}
+
+	ageDays := float64(now-createdAt) / 86400
+	if ageDays < 0 {
+		ageDays = 0
+	}
+
+	var curve float64
+	switch conf.Curve {
+	case BoostCurveLinear:
+		curve = 1 - ageDays/conf.BoostDays
+		if curve < 0 {
+			curve = 0
+		}
+	default:
+		curve = util.DecayWeight(now, createdAt, conf.BoostDays*86400)
+	}
+
+	return 1 + conf.Boost*curve
+}
+
+// CheckActivityConf reports whether conf has usable parameters.
+func CheckActivityConf(conf model.ActivityConf) error {
+	if conf.InactiveDays < 0 {
+		return fmt.Errorf("CheckActivityConf check fail, InactiveDays %v must not be negative", conf.InactiveDays)
+	}
+
+	if conf.RecencyWeight < 0 || conf.RecencyWeight > 1 {
+		return fmt.Errorf("CheckActivityConf check fail, RecencyWeight %v must be between 0 and 1", conf.RecencyWeight)
+	}
+
+	if conf.RecencyWeight > 0 && conf.RecencyHalfLifeDays <= 0 {
+		return fmt.Errorf("CheckActivityConf check fail, RecencyHalfLifeDays %v must be positive", conf.RecencyHalfLifeDays)
+	}
+
+	return nil
+}
+
+// ActivityFactor returns the factor the score of a user last signed in at
+// lastSignInAt is multiplied with: 1 for a user signed in just now, falling
+// towards 1-RecencyWeight with the configured half-life.
+func ActivityFactor(conf model.ActivityConf, now int64, lastSignInAt int64) float64 {
+	if conf.RecencyWeight == 0 {
+		return 1
+	}
+
+	return 1 - conf.RecencyWeight + conf.RecencyWeight*util.DecayWeight(now, lastSignInAt, conf.RecencyHalfLifeDays*86400)
+}
+
+const (
+	UserCapModeEvents   = "events"   // 按行为次数计数
+	UserCapModeDistinct = "distinct" // 每个用户最多计1次
+	UserCapModeLog      = "log"      // 按log(1+次数)计数
+)
+
+// CheckUserCapConf reports whether conf names a known mode with usable
+// parameters.
+func CheckUserCapConf(conf model.UserCapConf) error {
+	if conf.MaxActions < 0 {
+		return fmt.Errorf("CheckUserCapConf check fail, MaxActions %v must not be negative", conf.MaxActions)
+	}
+
+	if conf.OwnerWeight < 0 {
+		return fmt.Errorf("CheckUserCapConf check fail, OwnerWeight %v must not be negative", conf.OwnerWeight)
+	}
+
+	switch conf.Mode {
+	case UserCapModeEvents, UserCapModeDistinct, UserCapModeLog:
+	default:
+		return fmt.Errorf("CheckUserCapConf check fail, unknown Mode %q", conf.Mode)
+	}
+
+	return nil
+}
+
+// CapUserActions returns the indexes of the action times of a single user
+// that count: the most recent MaxActions of them, or only the most recent
+// one in distinct mode.
+func CapUserActions(conf model.UserCapConf, times []int64) []int {
+	indexes := make([]int, len(times))
+	for i, _ := range indexes {
+		indexes[i] = i
+	}
+
+	maxActions := conf.MaxActions
+	if conf.Mode == UserCapModeDistinct {
+		maxActions = 1
+	}
+
+	if maxActions == 0 || len(times) <= maxActions {
+		return indexes
+	}
+
+	sort.SliceStable(indexes, func(i, j int) bool { return times[indexes[i]] > times[indexes[j]] })
+
+	return indexes[:maxActions]
+}
+
+// ActionWeight returns the weight of the i-th action in weights, or 1 if the
+// actions carry no weights.
+func ActionWeight(weights []float64, i int) float64 {
+	if weights == nil {
+		return 1
+	}
+
+	return weights[i]
+}
+
+// DampenUserActions returns what the counted actions of a single user are
+// worth, value being their count or summed weight.
+func DampenUserActions(conf model.UserCapConf, value float64) float64 {
+	if conf.Mode == UserCapModeLog {
+		return math.Log1p(value)
+	}
+
+	return value
+}
+
+const (
+	RelationFollow = "follow" // 关注关系
+	RelationMember = "member" // 成员关系
+)
+
+// CheckRelationStatusConf reports whether every status has a name, a known
+// relation and a usable weight, and returns the confs by status.
+func CheckRelationStatusConf(conf []model.RelationStatusConf) (map[string]model.RelationStatusConf, error) {
+	statuses := make(map[string]model.RelationStatusConf)
+	for _, v := range conf {
+		if v.Status == "" {
+			return nil, fmt.Errorf("CheckRelationStatusConf check fail, empty Status")
+		}
+
+		switch v.Relation {
+		case RelationFollow, RelationMember:
+		default:
+			return nil, fmt.Errorf("CheckRelationStatusConf check fail, unknown Relation %q of %q", v.Relation, v.Status)
+		}
+
+		if v.Weight < 0 {
+			return nil, fmt.Errorf("CheckRelationStatusConf check fail, Weight %v of %q must not be negative", v.Weight, v.Status)
+		}
+
+		statuses[strings.ToLower(v.Status)] = v
+	}
+
+	return statuses, nil
+}
+
+// CheckCommentTypeConf reports whether every commentable type has a name and
+// a usable weight, and returns the weights by type.
+func CheckCommentTypeConf(conf []model.CommentTypeConf) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, v := range conf {
+		if v.Type == "" {
+			return nil, fmt.Errorf("CheckCommentTypeConf check fail, empty Type")
+		}
+
+		if v.Weight < 0 {
+			return nil, fmt.Errorf("CheckCommentTypeConf check fail, Weight %v of %q must not be negative", v.Weight, v.Type)
+		}
+
+		weights[strings.ToLower(v.Type)] = v.Weight
+	}
+
+	return weights, nil
+}
+
+// CommentWeight returns the weight of a comment on commentableType, 1 for
+// types without a configured weight.
+func CommentWeight(weights map[string]float64, commentableType string) float64 {
+	if v, ok := weights[commentableType]; ok {
+		return v
+	}
+
+	return 1
+}
+
+// AddFeature appends the additive feature name to features.
+func AddFeature(features []model.ScoreFeature, name string, value float64, weight float64) []model.ScoreFeature {
+	return append(features, model.ScoreFeature{Name: name, Value: value, Weight: weight, Contribution: value * weight})
+}
+
+// AddFactor appends the multiplicative factor name, applied to score, to
+// features.
+func AddFactor(features []model.ScoreFeature, name string, factor float64, score float64) []model.ScoreFeature {
+	return append(features, model.ScoreFeature{Name: name, Value: factor, Weight: score, Contribution: score*factor - score})
+}
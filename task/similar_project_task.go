@@ -0,0 +1,188 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"time"
+
+	"doraemon/model"
+	"doraemon/util"
+)
+
+func init() {
+	Register("SimilarProject", NewSimilarProjectTask())
+}
+
+// SimilarProjectTask finds the related projects of every project by the
+// cosine similarity of the TF-IDF vectors of their titles.
+type SimilarProjectTask struct {
+	*ProjectRecommendTask
+	ProjectTermMap map[int64]map[string]float64 // 项目标题的TF-IDF向量
+	TermProjectMap map[string][]int64           // 包含词语的项目
+}
+
+func NewSimilarProjectTask() *SimilarProjectTask {
+	return &SimilarProjectTask{
+		ProjectRecommendTask: NewProjectRecommendTask(),
+		ProjectTermMap:       make(map[int64]map[string]float64),
+		TermProjectMap:       make(map[string][]int64),
+	}
+}
+
+func (this *SimilarProjectTask) DoResult(result <-chan Result, output *os.File) error {
+	var number int = 0
+	for _ = range result {
+		number += 1
+
+		if number%10000 == 0 {
+			fmt.Printf("%d\t%s\n", number, time.Now().String())
+		}
+	}
+
+	// 输入文件读取失败或跳过的行过多时不生成结果
+	if this.InputErr != nil {
+		return this.InputErr
+	}
+
+	// 计算项目标题的TF-IDF向量
+	this.DoCalculateTfIdf()
+
+	items := make(map[int64]interface{})
+	for k, v := range this.ProjectInfoMap {
+		scores := make(map[int64]float64)
+		for term, weight := range this.ProjectTermMap[k] {
+			// 过于常见的词语没有区分度, 所有标题都有的词语权重为0
+			projects := this.TermProjectMap[term]
+			if weight == 0 || len(projects) > util.SimilarProjectMaxTermProjects {
+				continue
+			}
+
+			for _, projectId := range projects {
+				if projectId != k {
+					scores[projectId] += weight * this.ProjectTermMap[projectId][term]
+				}
+			}
+		}
+
+		if len(scores) == 0 {
+			continue
+		}
+
+		var projectRecommends []model.ProjectRecommend
+		for projectId, score := range scores {
+			var projectRecommend model.ProjectRecommend
+			projectRecommend.Id = projectId
+			projectRecommend.Title = this.ProjectInfoMap[projectId].Title
+			projectRecommend.UserId = this.ProjectInfoMap[projectId].UserId
+			projectRecommend.Score = score
+
+			projectRecommends = append(projectRecommends, projectRecommend)
+		}
+
+		util.DescByField(projectRecommends, "Score")
+		if int64(len(projectRecommends)) > util.MaxSimilarProjectCount {
+			projectRecommends = projectRecommends[:util.MaxSimilarProjectCount]
+		}
+
+		var similarProject model.SimilarProject
+		similarProject.Id = k
+		similarProject.Title = v.Title
+		similarProject.Projects = projectRecommends
+
+		data, err := json.Marshal(&similarProject)
+		if err != nil {
+			continue
+		}
+
+		data = append(data, '\n')
+		_, _ = output.WriteString(string(data))
+
+		items[k] = similarProject
+	}
+
+	// 将相似项目写入memcached
+	return PublishResult("SimilarProject", nil, items)
+}
+
+// DoCalculateTfIdf builds the L2 normalized TF-IDF vector of every title,
+// with tf the count of a term in the title and idf log(N/df).
+func (this *SimilarProjectTask) DoCalculateTfIdf() {
+	for k, v := range this.ProjectInfoMap {
+		terms := make(map[string]float64)
+		for _, term := range util.Tokenize(v.Title) {
+			terms[term] += 1
+		}
+
+		for term, _ := range terms {
+			this.TermProjectMap[term] = append(this.TermProjectMap[term], k)
+		}
+
+		this.ProjectTermMap[k] = terms
+	}
+
+	count := float64(len(this.ProjectInfoMap))
+	for _, terms := range this.ProjectTermMap {
+		var norm float64
+		for term, tf := range terms {
+			weight := tf * math.Log(count/float64(len(this.TermProjectMap[term])))
+			terms[term] = weight
+			norm += weight * weight
+		}
+
+		if norm == 0 {
+			continue
+		}
+
+		norm = math.Sqrt(norm)
+		for term, weight := range terms {
+			terms[term] = weight / norm
+		}
+	}
+}
+
+func (this *SimilarProjectTask) DoDataTask(inputFiles []string, outputFile string, arg interface{}) error {
+	// 检查输入参数信息
+	if len(inputFiles) < 1 {
+		return errors.New("DoDataTask SimilarProjectTask check fail, inputFiles len is not correct")
+	}
+
+	// 设置文件名称, 只需要项目信息
+	projectFile := inputFiles[0]
+
+	// 读取输入文件
+	input, err := OpenInput("projects", projectFile, &model.ProjectRecord{})
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	// 创建生成结果文件
+	output, err := os.OpenFile(outputFile, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	runtime.GOMAXPROCS(runtime.NumCPU())
+
+	jobs := make(chan Job, this.Workers)
+	done := make(chan struct{}, this.Workers)
+	result := make(chan Result, this.Workers)
+
+	// 将需要并发处理的任务添加到jobs的channel中
+	go this.AddJobs(jobs, result, input)
+
+	// 根据cpu的数量启动对应个数的goroutines从jobs争夺任务进行处理
+	for i := 0; i < this.Workers; i++ {
+		go this.DoJobs(done, jobs)
+	}
+
+	// 等待所有worker routiines的完成结果, 并将结果通知主routine
+	go this.AwaitJobDone(done, result)
+
+	return this.DoResult(result, output)
+}
@@ -6,7 +6,7 @@ import (
 
 // Job
 type Job struct {
-	data   string
+	data   interface{}
 	result chan<- Result
 }
 
@@ -19,7 +19,7 @@ func (this *Job) String() string {
 
 // Result
 type Result struct {
-	data string
+	data interface{}
 }
 
 func (this *Result) String() string {
@@ -0,0 +1,133 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"doraemon/model"
+	"doraemon/util"
+)
+
+func init() {
+	Register("UserInfluence", NewUserInfluenceTask())
+}
+
+// UserInfluenceTask scores every user by the PageRank of the follow graph,
+// so that a follow from an influential user counts more than one from an
+// account nobody follows.
+type UserInfluenceTask struct {
+	*UserRecommendTask
+}
+
+func NewUserInfluenceTask() *UserInfluenceTask {
+	return &UserInfluenceTask{
+		UserRecommendTask: NewUserRecommendTask(),
+	}
+}
+
+func (this *UserInfluenceTask) DoResult(result <-chan Result, output *os.File) error {
+	var number int = 0
+	for _ = range result {
+		number += 1
+
+		if number%10000 == 0 {
+			fmt.Printf("%d\t%s\n", number, time.Now().String())
+		}
+	}
+
+	// 输入文件读取失败或跳过的行过多时不生成结果
+	if this.InputErr != nil {
+		return this.InputErr
+	}
+
+	influences := this.DoCalculateInfluence()
+
+	var userInfluences []model.UserInfluence
+	items := make(map[int64]interface{})
+	for k, v := range this.UserInfoMap {
+		var userInfluence model.UserInfluence
+		userInfluence.Id = k
+		userInfluence.Name = v.Name
+		userInfluence.Score = influences[k]
+
+		userInfluences = append(userInfluences, userInfluence)
+		items[k] = userInfluence
+	}
+
+	util.DescByField(userInfluences, "Score")
+
+	for _, v := range userInfluences {
+		data, err := json.Marshal(&v)
+		if err != nil {
+			continue
+		}
+
+		data = append(data, '\n')
+		_, _ = output.WriteString(string(data))
+	}
+
+	// 将影响力得分写入memcached
+	return PublishResult("UserInfluence", nil, items)
+}
+
+func (this *UserInfluenceTask) DoDataTask(inputFiles []string, outputFile string, arg interface{}) error {
+	// 检查输入参数信息
+	if len(inputFiles) < 5 {
+		return errors.New("DoDataTask UserInfluenceTask check fail, inputFiles len is not correct")
+	}
+
+	// 设置文件名称, 创意和评论信息不参与计算
+	userFile := inputFiles[0]
+	userProfileFile := inputFiles[1]
+	userRelationFile := inputFiles[4]
+
+	var err error
+	// 处理用户简介信息
+	err = this.DoProcessUserProfileFile(userProfileFile)
+	if err != nil {
+		return err
+	}
+
+	// 处理用户关注信息
+	err = this.DoProcessUserRelationFile(userRelationFile)
+	if err != nil {
+		return err
+	}
+
+	// 读取输入文件
+	input, err := OpenInput("users", userFile, &model.UserRecord{})
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	// 创建生成结果文件
+	output, err := os.OpenFile(outputFile, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	runtime.GOMAXPROCS(runtime.NumCPU())
+
+	jobs := make(chan Job, this.Workers)
+	done := make(chan struct{}, this.Workers)
+	result := make(chan Result, this.Workers)
+
+	// 将需要并发处理的任务添加到jobs的channel中
+	go this.AddJobs(jobs, result, input)
+
+	// 根据cpu的数量启动对应个数的goroutines从jobs争夺任务进行处理
+	for i := 0; i < this.Workers; i++ {
+		go this.DoJobs(done, jobs)
+	}
+
+	// 等待所有worker routiines的完成结果, 并将结果通知主routine
+	go this.AwaitJobDone(done, result)
+
+	return this.DoResult(result, output)
+}
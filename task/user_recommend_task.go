@@ -1,19 +1,17 @@
 package task
 
 import (
-	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"runtime"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 
 	"doraemon/model"
+	"doraemon/modual/reader"
 	"doraemon/util"
 )
 
@@ -22,71 +20,71 @@ func init() {
 }
 
 type UserRecommendTask struct {
-	Workers         int
-	UserRalationMap map[int64]map[int64][]int64 // 用户被关注信息
-	UserIdeaMap     map[int64][]int64           // 用户创意信息
-	UserCommentMap  map[int64][]int64           // 用户评论信息
-	UserInfoMap     map[int64]*model.User       // 用户基本信息
-	Mutex           sync.Mutex
+	Workers            int
+	UserRalationMap    map[int64]map[int64][]int64 // 用户被关注信息
+	UserIdeaMap        map[int64][]int64           // 用户创意信息
+	UserCommentMap     map[int64][]int64           // 用户评论信息
+	UserCommentWeight  map[int64][]float64         // 用户评论的类型权重
+	UserReceivedMap    map[int64][]int64           // 用户创意收到的评论信息
+	UserReceivedWeight map[int64][]float64         // 用户创意收到评论的类型权重
+	IdeaUserMap        map[int64]int64             // 创意作者信息
+	UserInfoMap        map[int64]*model.User       // 用户基本信息
+	InputErr           error                       // 读取任务输入文件的错误
+	Mutex              sync.Mutex
 }
 
 func NewUserRecommendTask() *UserRecommendTask {
 	return &UserRecommendTask{
-		Workers:         1,
-		UserRalationMap: make(map[int64]map[int64][]int64),
-		UserIdeaMap:     make(map[int64][]int64),
-		UserCommentMap:  make(map[int64][]int64),
-		UserInfoMap:     make(map[int64]*model.User),
+		Workers:            1,
+		UserRalationMap:    make(map[int64]map[int64][]int64),
+		UserIdeaMap:        make(map[int64][]int64),
+		UserCommentMap:     make(map[int64][]int64),
+		UserCommentWeight:  make(map[int64][]float64),
+		UserReceivedMap:    make(map[int64][]int64),
+		UserReceivedWeight: make(map[int64][]float64),
+		IdeaUserMap:        make(map[int64]int64),
+		UserInfoMap:        make(map[int64]*model.User),
 	}
 }
 
 func (this *UserRecommendTask) DoJob(job Job) {
-	// id \t username \t last_sign_in_at \t created_at
-	fields := strings.Split(job.data, "\t")
-	if len(fields) != 4 {
-		return
-	}
-
-	id := fields[0]
-	username := fields[1]
-	last_sign_in_at := fields[2]
-	created_at := fields[3]
-
-	if username == "" || last_sign_in_at == "" || created_at == "" {
-		return
-	}
-
-	userId, err := strconv.ParseInt(id, 10, 64)
-	if err != nil {
-		return
-	}
+	record := job.data.(*model.UserRecord)
 
 	this.Mutex.Lock()
-	if v, ok := this.UserInfoMap[userId]; ok {
-		v.Name = username
-		v.Id = userId
-		v.LastSignInAt = util.ParseDateTime(last_sign_in_at).Unix()
+	if v, ok := this.UserInfoMap[record.Id]; ok {
+		v.Name = record.Username
+		v.Id = record.Id
+		v.LastSignInAt = record.LastSignInAt
 	} else {
 		user := &model.User{}
-		user.Id = userId
-		user.Name = username
-		user.LastSignInAt = util.ParseDateTime(last_sign_in_at).Unix()
-		this.UserInfoMap[userId] = user
+		user.Id = record.Id
+		user.Name = record.Username
+		user.LastSignInAt = record.LastSignInAt
+		this.UserInfoMap[record.Id] = user
 	}
 	defer this.Mutex.Unlock()
 
 	job.result <- Result{job.data}
 }
 
-func (this *UserRecommendTask) AddJobs(jobs chan<- Job, result chan<- Result, input *os.File) {
-	br := bufio.NewReader(input)
+func (this *UserRecommendTask) AddJobs(jobs chan<- Job, result chan<- Result, input *reader.Reader) {
+	var filtered int = 0
 	for {
-		line, err := br.ReadString('\n')
+		record := &model.UserRecord{}
+		err := input.Read(record)
 		if err == io.EOF {
+			// 检查被跳过的行数
+			fmt.Printf("input users\tfiltered never signed in\t%d\n", filtered)
+			this.InputErr = CheckInput("users", input)
 			break
+		} else if err != nil {
+			this.InputErr = err
+			break
+		} else if record.LastSignInAt == 0 {
+			// 从未登录的用户不参与推荐, 不计入被跳过的行
+			filtered += 1
 		} else {
-			realLine := strings.ToLower(strings.TrimRight(line, "\n"))
-			jobs <- Job{realLine, result}
+			jobs <- Job{record, result}
 		}
 	}
 
@@ -113,7 +111,7 @@ func (this *UserRecommendTask) AwaitJobDone(done <-chan struct{}, result chan<-
 	close(result)
 }
 
-func (this *UserRecommendTask) DoResult(result <-chan Result, output *os.File) {
+func (this *UserRecommendTask) DoResult(result <-chan Result, output *os.File) error {
 	var number int = 0
 	for _ = range result {
 		number += 1
@@ -123,35 +121,131 @@ func (this *UserRecommendTask) DoResult(result <-chan Result, output *os.File) {
 		}
 	}
 
+	// 输入文件读取失败或跳过的行过多时不生成结果
+	if this.InputErr != nil {
+		return this.InputErr
+	}
+
+	scoreConf := model.GlobalConf.UserRecommendScore
+	err := CheckScoreConf(scoreConf)
+	if err != nil {
+		return err
+	}
+
+	diversityConf := model.GlobalConf.UserRecommendDiversity
+	_, ok := UserSimilarities[diversityConf.Similarity]
+	err = CheckDiversityConf(diversityConf, ok)
+	if err != nil {
+		return err
+	}
+
+	activityConf := model.GlobalConf.UserRecommendActivity
+	err = CheckActivityConf(activityConf)
+	if err != nil {
+		return err
+	}
+
+	// 计算用户影响力得分
+	var influences map[int64]float64
+	if model.GlobalConf.UserRecommendInfluencePercent != 0 {
+		influences = this.DoCalculateInfluence()
+	}
+
 	var userRecommends []model.UserRecommend
+	items := make(map[int64]interface{})
+	verbose := model.GlobalConf.VerboseOutput
+	now := time.Now().Unix()
 	minFilterTime := time.Now().AddDate(0, 0, (-1)*util.UserRecommendFilterDayNum).Unix()
+	minSignInTime := now - int64(activityConf.InactiveDays*86400)
+	var inactiveCount int = 0
 	for k, v := range this.UserInfoMap {
-		// 获取用户创意数，最近的创意数量
-		ideasCount, recentIdeasCount := this.DoCalculateCount(this.UserIdeaMap, k, minFilterTime)
-
-		// 获取用户评论数，近期的评论数量
-		commentsCount, recentCommentsCount := this.DoCalculateCount(this.UserCommentMap, k, minFilterTime)
+		// 过滤长期未登录的用户
+		if activityConf.InactiveDays > 0 && v.LastSignInAt < minSignInTime {
+			inactiveCount += 1
+			continue
+		}
 
-		// 获取用户关注数量，最近的用户关注数量
-		usersCount, recentUsersCount := this.DoCalculateCount2(this.UserRalationMap, k, minFilterTime)
+		var score float64
+		var features []model.ScoreFeature
+		if scoreConf.Mode == ScoreModeWindow {
+			// 获取用户创意数，最近的创意数量
+			ideasCount, recentIdeasCount := this.DoCalculateCount(this.UserIdeaMap, nil, k, minFilterTime)
+
+			// 获取用户评论数，近期的评论数量
+			commentsCount, recentCommentsCount := this.DoCalculateCount(this.UserCommentMap, this.UserCommentWeight, k, minFilterTime)
+
+			// 获取用户创意收到的评论数，近期收到的评论数量
+			receivedCount, recentReceivedCount := this.DoCalculateCount(this.UserReceivedMap, this.UserReceivedWeight, k, minFilterTime)
+
+			// 获取用户关注数量，最近的用户关注数量
+			usersCount, recentUsersCount := this.DoCalculateCount2(this.UserRalationMap, k, minFilterTime)
+
+			// 计算项目得分
+			score = util.UserRecommendBasicPercent*(ideasCount+commentsCount+float64(usersCount)) + util.UserRecommendActionPercent*(recentIdeasCount+recentCommentsCount+float64(recentUsersCount))
+			score += model.GlobalConf.UserRecommendReceivedPercent * (util.UserRecommendBasicPercent*receivedCount + util.UserRecommendActionPercent*recentReceivedCount)
+
+			// 记录得分明细
+			if verbose {
+				features = AddFeature(features, "ideas", ideasCount, util.UserRecommendBasicPercent)
+				features = AddFeature(features, "recent_ideas", recentIdeasCount, util.UserRecommendActionPercent)
+				features = AddFeature(features, "comments", commentsCount, util.UserRecommendBasicPercent)
+				features = AddFeature(features, "recent_comments", recentCommentsCount, util.UserRecommendActionPercent)
+				features = AddFeature(features, "received_comments", receivedCount, model.GlobalConf.UserRecommendReceivedPercent*util.UserRecommendBasicPercent)
+				features = AddFeature(features, "recent_received_comments", recentReceivedCount, model.GlobalConf.UserRecommendReceivedPercent*util.UserRecommendActionPercent)
+				features = AddFeature(features, "followers", float64(usersCount), util.UserRecommendBasicPercent)
+				features = AddFeature(features, "recent_followers", float64(recentUsersCount), util.UserRecommendActionPercent)
+			}
+		} else {
+			// 按时间衰减后的创意、评论、关注数量计算用户得分
+			ideasWeight := this.DoCalculateWeight(this.UserIdeaMap, nil, k, now, scoreConf)
+			commentsWeight := this.DoCalculateWeight(this.UserCommentMap, this.UserCommentWeight, k, now, scoreConf)
+			receivedWeight := this.DoCalculateWeight(this.UserReceivedMap, this.UserReceivedWeight, k, now, scoreConf)
+			usersWeight := this.DoCalculateWeight2(this.UserRalationMap, k, now, scoreConf)
+			score = ideasWeight + commentsWeight + usersWeight
+			score += model.GlobalConf.UserRecommendReceivedPercent * receivedWeight
+
+			// 记录得分明细
+			if verbose {
+				features = AddFeature(features, "ideas", ideasWeight, 1)
+				features = AddFeature(features, "comments", commentsWeight, 1)
+				features = AddFeature(features, "received_comments", receivedWeight, model.GlobalConf.UserRecommendReceivedPercent)
+				features = AddFeature(features, "followers", usersWeight, 1)
+			}
+		}
+		score += model.GlobalConf.UserRecommendInfluencePercent * influences[k]
 
-		// 计算项目得分
-		score := util.UserRecommendBasicPercent*(float64)(ideasCount+commentsCount+usersCount) + util.UserRecommendActionPercent*(float64)(recentIdeasCount+recentCommentsCount+recentUsersCount)
+		if verbose && influences != nil {
+			features = AddFeature(features, "influence", influences[k], model.GlobalConf.UserRecommendInfluencePercent)
+		}
 
-		// data := fmt.Sprintf("%d\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%f\n", k, v.Name, v.Description, ideasCount, recentIdeasCount, commentsCount, recentCommentsCount, usersCount, recentUsersCount, score)
-		// fmt.Println(data)
+		// 按最近登录时间加权
+		activity := ActivityFactor(activityConf, now, v.LastSignInAt)
+		if verbose && activity != 1 {
+			features = AddFactor(features, "activity", activity, score)
+		}
+		score *= activity
 
 		var userRecommend model.UserRecommend
 		userRecommend.Id = k
 		userRecommend.Name = v.Name
 		userRecommend.Description = v.Description
 		userRecommend.Score = score
+		userRecommend.Features = features
 
 		userRecommends = append(userRecommends, userRecommend)
+		items[k] = userRecommend
 	}
 
+	fmt.Printf("users\t%d\tinactive users filtered\t%d\n", len(this.UserInfoMap), inactiveCount)
+
 	util.DescByField(userRecommends, "Score")
-	userRecommends = userRecommends[:util.MaxUserRecommendCount]
+
+	// 按多样性重排推荐结果
+	userRecommends = this.DoDiversify(userRecommends, diversityConf)
+
+	if int64(len(userRecommends)) > util.MaxUserRecommendCount {
+		userRecommends = userRecommends[:util.MaxUserRecommendCount]
+	}
 
 	for _, v := range userRecommends {
 		data, err := json.Marshal(&v)
@@ -162,207 +256,168 @@ func (this *UserRecommendTask) DoResult(result <-chan Result, output *os.File) {
 		data = append(data, '\n')
 		_, _ = output.WriteString(string(data))
 	}
+
+	// 将推荐结果写入memcached
+	return PublishResult("UserRecommend", userRecommends, items)
 }
 
 func (this *UserRecommendTask) DoProcessIdeaFile(inputFile string) error {
-	input, err := os.OpenFile(inputFile, os.O_RDONLY, 0)
+	input, err := OpenInput("ideas", inputFile, &model.IdeaRecord{})
 	if err != nil {
 		return err
 	}
 	defer input.Close()
 
-	br := bufio.NewReader(input)
 	for {
-		line, err := br.ReadString('\n')
+		record := &model.IdeaRecord{}
+		err := input.Read(record)
 		if err == io.EOF {
 			break
-		} else {
-			realLine := strings.ToLower(strings.TrimRight(line, "\n"))
-
-			// id \t project_id \t user_id \t created_at
-			fields := strings.Split(realLine, "\t")
-			if len(fields) != 4 {
-				continue
-			}
-
-			projectId := fields[1]
-			userId := fields[2]
-			createdAt := fields[3]
-
-			if projectId == "" || userId == "" || createdAt == "" {
-				continue
-			}
+		} else if err != nil {
+			return err
+		}
 
-			var err error
-			userIdNum, err := strconv.ParseInt(userId, 10, 64)
-			if err != nil {
-				continue
-			}
+		// 记录创意作者, 用于统计创意收到的评论
+		if record.Id != 0 {
+			this.IdeaUserMap[record.Id] = record.UserId
+		}
 
-			createdAtTime := util.ParseDateTime(createdAt).Unix()
+		this.UserIdeaMap[record.UserId] = append(this.UserIdeaMap[record.UserId], record.CreatedAt)
+	}
 
-			if v, ok := this.UserIdeaMap[userIdNum]; ok {
-				v = append(v, createdAtTime)
-			} else {
-				var times []int64
-				times = append(times, createdAtTime)
-				this.UserIdeaMap[userIdNum] = times
-			}
-		}
+	// 检查被跳过的行数
+	err = CheckInput("ideas", input)
+	if err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// DoProcessCommentFile counts every comment for the commenter, weighted by
+// its commentable_type. Comments on ideas also count for the author of the
+// idea as received engagement, so the idea file has to be processed first.
 func (this *UserRecommendTask) DoProcessCommentFile(inputFile string) error {
-	input, err := os.OpenFile(inputFile, os.O_RDONLY, 0)
+	typeWeights, err := CheckCommentTypeConf(model.GlobalConf.CommentTypes)
+	if err != nil {
+		return err
+	}
+
+	input, err := OpenInput("comments", inputFile, &model.CommentRecord{})
 	if err != nil {
 		return err
 	}
 	defer input.Close()
 
-	br := bufio.NewReader(input)
 	for {
-		line, err := br.ReadString('\n')
+		record := &model.CommentRecord{}
+		err := input.Read(record)
 		if err == io.EOF {
 			break
-		} else {
-			realLine := strings.ToLower(strings.TrimRight(line, "\n"))
+		} else if err != nil {
+			return err
+		}
 
-			// id \t project_id \t user_id \t commentable_id \t commentable_type \t created_at
-			fields := strings.Split(realLine, "\t")
-			if len(fields) != 6 {
-				continue
-			}
+		weight := CommentWeight(typeWeights, record.CommentableType)
 
-			projectId := fields[1]
-			userId := fields[2]
-			createdAt := fields[5]
+		this.UserCommentMap[record.UserId] = append(this.UserCommentMap[record.UserId], record.CreatedAt)
+		this.UserCommentWeight[record.UserId] = append(this.UserCommentWeight[record.UserId], weight)
 
-			if projectId == "" || userId == "" || createdAt == "" {
-				continue
-			}
+		// 创意评论同时计入创意作者收到的评论, 不包括作者自己的评论
+		if record.CommentableType != "idea" {
+			continue
+		}
 
-			var err error
-			userIdNum, err := strconv.ParseInt(userId, 10, 64)
-			if err != nil {
-				continue
-			}
+		authorId, ok := this.IdeaUserMap[record.CommentableId]
+		if !ok || authorId == record.UserId {
+			continue
+		}
 
-			createdAtTime := util.ParseDateTime(createdAt).Unix()
+		this.UserReceivedMap[authorId] = append(this.UserReceivedMap[authorId], record.CreatedAt)
+		this.UserReceivedWeight[authorId] = append(this.UserReceivedWeight[authorId], weight)
+	}
 
-			if v, ok := this.UserCommentMap[userIdNum]; ok {
-				v = append(v, createdAtTime)
-			} else {
-				var times []int64
-				times = append(times, createdAtTime)
-				this.UserCommentMap[userIdNum] = times
-			}
-		}
+	// 检查被跳过的行数
+	err = CheckInput("comments", input)
+	if err != nil {
+		return err
 	}
 
 	return nil
 }
 
 func (this *UserRecommendTask) DoProcessUserProfileFile(inputFile string) error {
-	input, err := os.OpenFile(inputFile, os.O_RDONLY, 0)
+	input, err := OpenInput("profiles", inputFile, &model.ProfileRecord{})
 	if err != nil {
 		return err
 	}
 	defer input.Close()
 
-	br := bufio.NewReader(input)
+	var filtered int = 0
 	for {
-		line, err := br.ReadString('\n')
+		record := &model.ProfileRecord{}
+		err := input.Read(record)
 		if err == io.EOF {
 			break
-		} else {
-			realLine := strings.ToLower(strings.TrimRight(line, "\n"))
-
-			// id \t user_id \t description \t created_at
-			fields := strings.Split(realLine, "\t")
-			if len(fields) != 4 {
-				continue
-			}
-
-			userId := fields[1]
-			description := fields[2]
-			createdAt := fields[3]
+		} else if err != nil {
+			return err
+		}
 
-			if userId == "" || description == "" || createdAt == "" {
-				continue
-			}
+		// 没有简介的用户资料不使用, 不计入被跳过的行
+		if record.Description == "" {
+			filtered += 1
+			continue
+		}
 
-			var err error
-			userIdNum, err := strconv.ParseInt(userId, 10, 64)
-			if err != nil {
-				continue
-			}
+		user := &model.User{}
+		user.Id = record.UserId
+		user.Description = record.Description
+		this.UserInfoMap[record.UserId] = user
+	}
 
-			user := &model.User{}
-			user.Id = userIdNum
-			user.Description = description
-			this.UserInfoMap[userIdNum] = user
-		}
+	// 检查被跳过的行数
+	fmt.Printf("input profiles\tfiltered empty description\t%d\n", filtered)
+	err = CheckInput("profiles", input)
+	if err != nil {
+		return err
 	}
 
 	return nil
 }
 
 func (this *UserRecommendTask) DoProcessUserRelationFile(inputFile string) error {
-	input, err := os.OpenFile(inputFile, os.O_RDONLY, 0)
+	input, err := OpenInput("relationships", inputFile, &model.RelationshipRecord{})
 	if err != nil {
 		return err
 	}
 	defer input.Close()
 
-	br := bufio.NewReader(input)
 	for {
-		line, err := br.ReadString('\n')
+		record := &model.RelationshipRecord{}
+		err := input.Read(record)
 		if err == io.EOF {
 			break
-		} else {
-			realLine := strings.ToLower(strings.TrimRight(line, "\n"))
-
-			// id \t follower_id \t followed_id \t created_at
-			fields := strings.Split(realLine, "\t")
-			if len(fields) != 4 {
-				continue
-			}
-
-			followerId := fields[1]
-			followedId := fields[2]
-			createdAt := fields[3]
-
-			if followerId == "" || followedId == "" || createdAt == "" {
-				continue
-			}
-
-			var err error
-			followerIdNum, err := strconv.ParseInt(followerId, 10, 64)
-			if err != nil {
-				continue
-			}
-
-			followedIdNum, err := strconv.ParseInt(followedId, 10, 64)
-			if err != nil {
-				continue
-			}
-
-			createdAtTime := util.ParseDateTime(createdAt).Unix()
+		} else if err != nil {
+			return err
+		}
 
-			if v, ok := this.UserRalationMap[followedIdNum]; ok {
-				v[followerIdNum] = append(v[followerIdNum], createdAtTime)
-			} else {
-				var times []int64
-				times = append(times, createdAtTime)
-				subMap := make(map[int64][]int64)
-				subMap[followerIdNum] = times
-				this.UserRalationMap[followedIdNum] = subMap
-			}
+		if v, ok := this.UserRalationMap[record.FollowedId]; ok {
+			v[record.FollowerId] = append(v[record.FollowerId], record.CreatedAt)
+		} else {
+			var times []int64
+			times = append(times, record.CreatedAt)
+			subMap := make(map[int64][]int64)
+			subMap[record.FollowerId] = times
+			this.UserRalationMap[record.FollowedId] = subMap
 		}
 	}
 
+	// 检查被跳过的行数
+	err = CheckInput("relationships", input)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -384,15 +439,19 @@ func (this *UserRecommendTask) DoCalculateCount2(data map[int64]map[int64][]int6
 	return countA, countB
 }
 
-func (this *UserRecommendTask) DoCalculateCount(data map[int64][]int64, key int64, minFilterTime int64) (int64, int64) {
-	var countA, countB int64
+// DoCalculateCount returns the number of events of key and the number of
+// those since minFilterTime, each event counted with its weight in weights,
+// if any.
+func (this *UserRecommendTask) DoCalculateCount(data map[int64][]int64, weights map[int64][]float64, key int64, minFilterTime int64) (float64, float64) {
+	var countA, countB float64
 
 	if v, ok := data[key]; ok {
-		for _, vv := range v {
-			countA += 1
+		for i, vv := range v {
+			weight := ActionWeight(weights[key], i)
+			countA += weight
 
 			if vv >= minFilterTime {
-				countB += 1
+				countB += weight
 			}
 		}
 	}
@@ -400,6 +459,92 @@ func (this *UserRecommendTask) DoCalculateCount(data map[int64][]int64, key int6
 	return countA, countB
 }
 
+// DoDiversify re-ranks the first CandidateCount entries of the sorted
+// userRecommends, so that similar users, e.g. users followed by the same
+// circle, do not crowd out everything else.
+func (this *UserRecommendTask) DoDiversify(userRecommends []model.UserRecommend, diversityConf model.DiversityConf) []model.UserRecommend {
+	if diversityConf.Mode == DiversityModeNone {
+		return userRecommends
+	}
+
+	count := diversityConf.CandidateCount
+	if count > len(userRecommends) {
+		count = len(userRecommends)
+	}
+
+	candidates := userRecommends[:count]
+	relevance := make([]float64, count)
+	for i, v := range candidates {
+		relevance[i] = v.Score
+	}
+
+	similarity := UserSimilarities[diversityConf.Similarity]
+	order := Diversify(diversityConf, relevance, func(i, j int) float64 {
+		return similarity(this, this.UserInfoMap[candidates[i].Id], this.UserInfoMap[candidates[j].Id])
+	})
+
+	ret := make([]model.UserRecommend, 0, len(userRecommends))
+	for _, i := range order {
+		ret = append(ret, candidates[i])
+	}
+
+	return append(ret, userRecommends[count:]...)
+}
+
+// DoCalculateWeight2 sums the weights of the events of key in a two level
+// map, each event weighted by its age as selected by scoreConf.
+func (this *UserRecommendTask) DoCalculateWeight2(data map[int64]map[int64][]int64, key int64, now int64, scoreConf model.ScoreConf) float64 {
+	var weight float64
+
+	if v, ok := data[key]; ok {
+		for _, vv := range v {
+			for _, vvv := range vv {
+				weight += EventWeight(scoreConf, now, vvv)
+			}
+		}
+	}
+
+	return weight
+}
+
+// DoCalculateWeight sums the weights of the events of key, each event
+// weighted by its age as selected by scoreConf and by its weight in weights,
+// if any.
+func (this *UserRecommendTask) DoCalculateWeight(data map[int64][]int64, weights map[int64][]float64, key int64, now int64, scoreConf model.ScoreConf) float64 {
+	var weight float64
+
+	if v, ok := data[key]; ok {
+		for i, vv := range v {
+			weight += ActionWeight(weights[key], i) * EventWeight(scoreConf, now, vv)
+		}
+	}
+
+	return weight
+}
+
+// DoCalculateInfluence runs PageRank over the follow graph, where every
+// follow is a link from the follower to the followed user. The ranks are
+// scaled by the number of users, so that an average user scores 1.
+func (this *UserRecommendTask) DoCalculateInfluence() map[int64]float64 {
+	edges := make(map[int64][]int64)
+	for followedId, followers := range this.UserRalationMap {
+		if _, ok := edges[followedId]; !ok {
+			edges[followedId] = nil
+		}
+
+		for followerId, _ := range followers {
+			edges[followerId] = append(edges[followerId], followedId)
+		}
+	}
+
+	ranks := util.PageRank(edges, model.GlobalConf.PageRankDamping, model.GlobalConf.PageRankIterations, model.GlobalConf.PageRankEpsilon)
+	for k, v := range ranks {
+		ranks[k] = v * float64(len(ranks))
+	}
+
+	return ranks
+}
+
 func (this *UserRecommendTask) DoDataTask(inputFiles []string, outputFile string, arg interface{}) error {
 	// 检查输入参数信息
 	if len(inputFiles) < 5 {
@@ -439,7 +584,7 @@ func (this *UserRecommendTask) DoDataTask(inputFiles []string, outputFile string
 	}
 
 	// 读取输入文件
-	input, err := os.OpenFile(userFile, os.O_RDONLY, 0)
+	input, err := OpenInput("users", userFile, &model.UserRecord{})
 	if err != nil {
 		return err
 	}
@@ -469,6 +614,5 @@ func (this *UserRecommendTask) DoDataTask(inputFiles []string, outputFile string
 	// 等待所有worker routiines的完成结果, 并将结果通知主routine
 	go this.AwaitJobDone(done, result)
 
-	this.DoResult(result, output)
-	return nil
+	return this.DoResult(result, output)
 }
@@ -5,9 +5,22 @@ var ProjectRecommendBasicPercent float64 = 0.6
 var ProjectRecommendActionPercent float64 = 0.4
 var MaxProjectRecommendCount int64 = 15
 
+var MaxPersonalProjectRecommendCount int64 = 15
+var PersonalProjectNeighborCount int = 50
+var PersonalProjectMaxUserProjects int = 500
+
+var MaxAlsProjectRecommendCount int = 15
+
+var MaxSimilarProjectCount int64 = 10
+var SimilarProjectMaxTermProjects int = 1000
+
 var UserRecommendFilterDayNum int = 30
 var UserRecommendBasicPercent float64 = 0.6
 var UserRecommendActionPercent float64 = 0.4
 var MaxUserRecommendCount int64 = 15
 
+var MaxPersonalUserRecommendCount int64 = 15
+var PersonalUserMaxFollowing int = 1000
+
 var MemcachedTimeout int = 1000
+var MemcachedItemSize int = 1000 * 1024
@@ -0,0 +1,25 @@
+package util
+
+import (
+	"math"
+)
+
+// DecayWeight returns the weight of an event createdAt seconds since the
+// epoch, halving every halfLife seconds. Events from the future weigh 1.
+func DecayWeight(now int64, createdAt int64, halfLife float64) float64 {
+	age := float64(now - createdAt)
+	if age <= 0 {
+		return 1
+	}
+	return math.Pow(0.5, age/halfLife)
+}
+
+// GravityWeight returns the Hacker News style weight 1/(hours+2)^gravity of
+// an event that is hours old.
+func GravityWeight(now int64, createdAt int64, gravity float64) float64 {
+	hours := float64(now-createdAt) / 3600
+	if hours < 0 {
+		hours = 0
+	}
+	return 1 / math.Pow(hours+2, gravity)
+}
@@ -0,0 +1,94 @@
+package util
+
+// MMR orders the items 0..n-1 by maximal marginal relevance: each step picks
+// the item maximizing
+//
+//	lambda*relevance(i) - (1-lambda)*max similarity(i, j)
+//
+// over the items j picked before. Relevance is scaled into [0, 1] by the
+// largest relevance, so that it is comparable with similarities in [0, 1].
+func MMR(relevance []float64, lambda float64, similarity func(i, j int) float64) []int {
+	n := len(relevance)
+
+	var maxRelevance float64
+	for _, v := range relevance {
+		if v > maxRelevance {
+			maxRelevance = v
+		}
+	}
+	if maxRelevance == 0 {
+		maxRelevance = 1
+	}
+
+	picked := make([]bool, n)
+	maxSimilarity := make([]float64, n)
+	order := make([]int, 0, n)
+	for len(order) < n {
+		best := -1
+		var bestScore float64
+		for i := 0; i < n; i++ {
+			if picked[i] {
+				continue
+			}
+
+			score := lambda*relevance[i]/maxRelevance - (1-lambda)*maxSimilarity[i]
+			if best < 0 || score > bestScore {
+				best = i
+				bestScore = score
+			}
+		}
+
+		picked[best] = true
+		order = append(order, best)
+
+		for i := 0; i < n; i++ {
+			if !picked[i] {
+				if v := similarity(i, best); v > maxSimilarity[i] {
+					maxSimilarity[i] = v
+				}
+			}
+		}
+	}
+
+	return order
+}
+
+// CapSimilar orders the items 0..n-1, which are sorted by relevance, so that
+// an item comes after all others once maxSimilar items before it have a
+// similarity of at least threshold with it.
+func CapSimilar(n int, threshold float64, maxSimilar int, similarity func(i, j int) float64) []int {
+	order := make([]int, 0, n)
+	var capped []int
+	for i := 0; i < n; i++ {
+		var count int
+		for _, j := range order {
+			if similarity(i, j) >= threshold {
+				count += 1
+			}
+		}
+
+		if count >= maxSimilar {
+			capped = append(capped, i)
+		} else {
+			order = append(order, i)
+		}
+	}
+
+	return append(order, capped...)
+}
+
+// Jaccard returns |a ∩ b| / |a ∪ b| of two term sets.
+func Jaccard(a map[string]bool, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+
+	var common int
+	for k, _ := range a {
+		if b[k] {
+			common += 1
+		}
+	}
+
+	return float64(common) / float64(len(a)+len(b)-common)
+}
@@ -0,0 +1,79 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+// groupSimilarity returns a similarity of 1 between items of the same group
+// and 0 otherwise.
+func groupSimilarity(groups []int) func(i, j int) float64 {
+	return func(i, j int) float64 {
+		if groups[i] == groups[j] {
+			return 1
+		}
+		return 0
+	}
+}
+
+func TestMMR(t *testing.T) {
+	tests := []struct {
+		name      string
+		relevance []float64
+		groups    []int
+		lambda    float64
+		want      []int
+	}{
+		{"empty", nil, nil, 0.5, []int{}},
+		{"relevance only", []float64{1, 3, 2}, []int{0, 0, 0}, 1, []int{1, 2, 0}},
+		{"diverse", []float64{10, 9, 8, 7}, []int{0, 0, 1, 1}, 0.5, []int{0, 2, 1, 3}},
+		{"zero relevance", []float64{0, 0, 0}, []int{0, 0, 1}, 0.5, []int{0, 2, 1}},
+	}
+
+	for _, v := range tests {
+		order := MMR(v.relevance, v.lambda, groupSimilarity(v.groups))
+		if !reflect.DeepEqual(order, v.want) {
+			t.Errorf("%s: MMR = %v, want %v", v.name, order, v.want)
+		}
+	}
+}
+
+func TestCapSimilar(t *testing.T) {
+	tests := []struct {
+		name       string
+		groups     []int
+		maxSimilar int
+		want       []int
+	}{
+		{"empty", nil, 1, []int{}},
+		{"no similar", []int{0, 1, 2}, 1, []int{0, 1, 2}},
+		{"cap 1", []int{0, 0, 1, 0, 1}, 1, []int{0, 2, 1, 3, 4}},
+		{"cap 2", []int{0, 0, 0, 1, 0}, 2, []int{0, 1, 3, 2, 4}},
+	}
+
+	for _, v := range tests {
+		order := CapSimilar(len(v.groups), 0.5, v.maxSimilar, groupSimilarity(v.groups))
+		if !reflect.DeepEqual(order, v.want) {
+			t.Errorf("%s: CapSimilar = %v, want %v", v.name, order, v.want)
+		}
+	}
+}
+
+func TestJaccard(t *testing.T) {
+	tests := []struct {
+		a    map[string]bool
+		b    map[string]bool
+		want float64
+	}{
+		{nil, nil, 0},
+		{map[string]bool{"a": true}, nil, 0},
+		{map[string]bool{"a": true, "b": true}, map[string]bool{"b": true, "c": true}, 1.0 / 3},
+		{map[string]bool{"a": true}, map[string]bool{"a": true}, 1},
+	}
+
+	for _, v := range tests {
+		if similarity := Jaccard(v.a, v.b); similarity != v.want {
+			t.Errorf("Jaccard(%v, %v) = %v, want %v", v.a, v.b, similarity, v.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package util
+
+import (
+	"errors"
+	"math"
+)
+
+// CholeskySolve solves a*x = b for a symmetric positive definite n×n matrix
+// a, stored row by row. Neither a nor b is modified.
+func CholeskySolve(a []float64, b []float64, n int) ([]float64, error) {
+	// a = l*l', l为下三角矩阵
+	l := make([]float64, n*n)
+	for i := 0; i < n; i++ {
+		for j := 0; j <= i; j++ {
+			sum := a[i*n+j]
+			for k := 0; k < j; k++ {
+				sum -= l[i*n+k] * l[j*n+k]
+			}
+
+			if i == j {
+				if sum <= 0 {
+					return nil, errors.New("CholeskySolve check fail, matrix is not positive definite")
+				}
+				l[i*n+i] = math.Sqrt(sum)
+			} else {
+				l[i*n+j] = sum / l[j*n+j]
+			}
+		}
+	}
+
+	// l*y = b
+	y := make([]float64, n)
+	for i := 0; i < n; i++ {
+		sum := b[i]
+		for k := 0; k < i; k++ {
+			sum -= l[i*n+k] * y[k]
+		}
+		y[i] = sum / l[i*n+i]
+	}
+
+	// l'*x = y
+	x := make([]float64, n)
+	for i := n - 1; i >= 0; i-- {
+		sum := y[i]
+		for k := i + 1; k < n; k++ {
+			sum -= l[k*n+i] * x[k]
+		}
+		x[i] = sum / l[i*n+i]
+	}
+
+	return x, nil
+}
+
+// Dot returns the inner product of a and b.
+func Dot(a []float64, b []float64) float64 {
+	var sum float64
+	for i, v := range a {
+		sum += v * b[i]
+	}
+	return sum
+}
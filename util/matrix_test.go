@@ -0,0 +1,82 @@
+package util
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCholeskySolve(t *testing.T) {
+	tests := []struct {
+		name string
+		a    []float64
+		b    []float64
+		n    int
+		want []float64
+		err  bool
+	}{
+		{
+			name: "identity",
+			a:    []float64{1, 0, 0, 1},
+			b:    []float64{3, -2},
+			n:    2,
+			want: []float64{3, -2},
+		},
+		{
+			name: "2x2",
+			a:    []float64{4, 2, 2, 3},
+			b:    []float64{2, 1},
+			n:    2,
+			want: []float64{0.5, 0},
+		},
+		{
+			name: "3x3",
+			a:    []float64{4, 12, -16, 12, 37, -43, -16, -43, 98},
+			b:    []float64{0, 6, 39},
+			n:    3,
+			want: []float64{1, 1, 1},
+		},
+		{
+			name: "not positive definite",
+			a:    []float64{1, 2, 2, 1},
+			b:    []float64{1, 1},
+			n:    2,
+			err:  true,
+		},
+	}
+
+	for _, v := range tests {
+		a := append([]float64(nil), v.a...)
+		b := append([]float64(nil), v.b...)
+
+		x, err := CholeskySolve(a, b, v.n)
+		if v.err {
+			if err == nil {
+				t.Errorf("%s: got %v, want an error", v.name, x)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", v.name, err)
+			continue
+		}
+
+		for i, want := range v.want {
+			if math.Abs(x[i]-want) > 1e-9 {
+				t.Errorf("%s: x[%d] is %v, want %v", v.name, i, x[i], want)
+			}
+		}
+
+		for i, _ := range v.a {
+			if a[i] != v.a[i] {
+				t.Errorf("%s: a was modified", v.name)
+				break
+			}
+		}
+		for i, _ := range v.b {
+			if b[i] != v.b[i] {
+				t.Errorf("%s: b was modified", v.name)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package util
+
+import (
+	"math"
+)
+
+// PageRank returns the PageRank of every node of the directed graph whose
+// edges[from] lists the nodes linked from. The rank of a node without
+// outgoing links is spread over all nodes. Iteration stops after
+// maxIterations rounds, or as soon as the ranks move less than epsilon in
+// total. The returned ranks add up to 1.
+func PageRank(edges map[int64][]int64, damping float64, maxIterations int, epsilon float64) map[int64]float64 {
+	ranks := make(map[int64]float64)
+	for from, v := range edges {
+		ranks[from] = 0
+		for _, to := range v {
+			ranks[to] = 0
+		}
+	}
+
+	count := float64(len(ranks))
+	if count == 0 {
+		return ranks
+	}
+
+	for k, _ := range ranks {
+		ranks[k] = 1 / count
+	}
+
+	for i := 0; i < maxIterations; i++ {
+		// 无出链节点的得分平均分配给所有节点
+		var dangling float64
+		for k, v := range ranks {
+			if len(edges[k]) == 0 {
+				dangling += v
+			}
+		}
+
+		base := (1-damping)/count + damping*dangling/count
+		next := make(map[int64]float64, len(ranks))
+		for k, _ := range ranks {
+			next[k] = base
+		}
+
+		for from, v := range edges {
+			if len(v) == 0 {
+				continue
+			}
+
+			share := damping * ranks[from] / float64(len(v))
+			for _, to := range v {
+				next[to] += share
+			}
+		}
+
+		var delta float64
+		for k, v := range next {
+			delta += math.Abs(v - ranks[k])
+		}
+
+		ranks = next
+		if delta < epsilon {
+			break
+		}
+	}
+
+	return ranks
+}
@@ -0,0 +1,57 @@
+package util
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPageRank(t *testing.T) {
+	tests := []struct {
+		name  string
+		edges map[int64][]int64
+		want  map[int64]float64
+	}{
+		{
+			name:  "empty",
+			edges: map[int64][]int64{},
+			want:  map[int64]float64{},
+		},
+		{
+			name:  "cycle",
+			edges: map[int64][]int64{1: {2}, 2: {3}, 3: {1}},
+			want:  map[int64]float64{1: 1.0 / 3, 2: 1.0 / 3, 3: 1.0 / 3},
+		},
+		{
+			name:  "dangling",
+			edges: map[int64][]int64{1: {2}},
+			want:  map[int64]float64{1: 0.5 / 1.425, 2: 1 - 0.5/1.425},
+		},
+		{
+			name:  "star",
+			edges: map[int64][]int64{2: {1}, 3: {1}, 1: {2, 3}},
+			want:  map[int64]float64{1: 0.4864864864864865, 2: 0.25675675675675674, 3: 0.25675675675675674},
+		},
+	}
+
+	for _, v := range tests {
+		ranks := PageRank(v.edges, 0.85, 1000, 1e-12)
+		if len(ranks) != len(v.want) {
+			t.Errorf("%s: got %d ranks, want %d", v.name, len(ranks), len(v.want))
+			continue
+		}
+
+		var sum float64
+		for _, rank := range ranks {
+			sum += rank
+		}
+		if len(ranks) > 0 && math.Abs(sum-1) > 1e-9 {
+			t.Errorf("%s: ranks add up to %v, want 1", v.name, sum)
+		}
+
+		for k, want := range v.want {
+			if math.Abs(ranks[k]-want) > 1e-9 {
+				t.Errorf("%s: rank of %d is %v, want %v", v.name, k, ranks[k], want)
+			}
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package util
+
+import (
+	"math"
+)
+
+// BayesianAverage shrinks the average sum/count towards priorMean, as if
+// priorWeight more samples of priorMean had been seen.
+func BayesianAverage(sum float64, count float64, priorMean float64, priorWeight float64) float64 {
+	if count+priorWeight == 0 {
+		return 0
+	}
+	return (sum + priorWeight*priorMean) / (count + priorWeight)
+}
+
+// WilsonLowerBound returns the lower bound of the Wilson score interval of
+// the rate positive/total at the confidence level given by z.
+func WilsonLowerBound(positive float64, total float64, z float64) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	p := positive / total
+	z2 := z * z
+	center := p + z2/(2*total)
+	margin := z * math.Sqrt(p*(1-p)/total+z2/(4*total*total))
+
+	return (center - margin) / (1 + z2/total)
+}
@@ -107,13 +107,3 @@ func GetMinTimeNow() string {
 	ret := year + month + day + hour + min
 	return ret
 }
-
-func ParseDate(value string) time.Time {
-	t, _ := time.Parse(FORMAT_DATE, value)
-	return t
-}
-
-func ParseDateTime(value string) time.Time {
-	t, _ := time.Parse(FORMAT_DATE_TIME, value)
-	return t
-}
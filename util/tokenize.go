@@ -0,0 +1,51 @@
+package util
+
+import (
+	"unicode"
+)
+
+// Tokenize splits text into terms. Runs of letters and digits become one
+// term each. Chinese text has no spaces between words, so a run of Han
+// characters becomes its overlapping character bigrams instead, or the
+// character itself if the run is a single character. Everything else
+// separates terms.
+func Tokenize(text string) []string {
+	var terms []string
+	var word []rune
+	var han []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			terms = append(terms, string(word))
+			word = word[:0]
+		}
+	}
+
+	flushHan := func() {
+		if len(han) == 1 {
+			terms = append(terms, string(han))
+		}
+		for i := 0; i+1 < len(han); i++ {
+			terms = append(terms, string(han[i:i+2]))
+		}
+		han = han[:0]
+	}
+
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) {
+			flushWord()
+			han = append(han, r)
+		} else if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			flushHan()
+			word = append(word, unicode.ToLower(r))
+		} else {
+			flushWord()
+			flushHan()
+		}
+	}
+
+	flushWord()
+	flushHan()
+
+	return terms
+}
@@ -0,0 +1,29 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"Hello, World 2024", []string{"hello", "world", "2024"}},
+		{"GoLang_v2!", []string{"golang", "v2"}},
+		{"人", []string{"人"}},
+		{"推荐系统", []string{"推荐", "荐系", "系统"}},
+		{"AI推荐 app", []string{"ai", "推荐", "app"}},
+		{"开源-项目", []string{"开源", "项目"}},
+		{"a人b", []string{"a", "人", "b"}},
+	}
+
+	for _, v := range tests {
+		terms := Tokenize(v.text)
+		if !reflect.DeepEqual(terms, v.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", v.text, terms, v.want)
+		}
+	}
+}